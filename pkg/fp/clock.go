@@ -0,0 +1,28 @@
+package fp
+
+import "time"
+
+// Clock provides the current time and timers to time-based operators,
+// so they can be driven by a fake clock in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+// SystemClock returns the real wall clock
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
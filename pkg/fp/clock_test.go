@@ -0,0 +1,138 @@
+package fp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose timers fire at once, moving the time forward,
+// so time-based code runs without sleeping
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+// newFakeClock returns a fake clock set to the Unix epoch
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+// Now returns the current fake time
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After moves the time forward by d and returns a channel that has already fired
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	fired := make(chan time.Time, 1)
+	fired <- c.now
+	return fired
+}
+
+// Advance moves the time forward by d, as if some work took that long
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Waits returns the durations passed to After, in order
+func (c *fakeClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+// manualClock is a Clock that moves only when Advance is called, which fires
+// the timers that fall due, so tests decide exactly when time passes
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+	afters int
+	// changed is closed and replaced whenever After is called
+	changed chan struct{}
+}
+
+// manualTimer is a timer of a manualClock that has not fired yet
+type manualTimer struct {
+	due   time.Time
+	fired chan time.Time
+}
+
+// newManualClock returns a manual clock set to the Unix epoch
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Unix(0, 0), changed: make(chan struct{})}
+}
+
+// Now returns the current manual time
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that fires once Advance reaches now + d
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fired := make(chan time.Time, 1)
+	if d <= 0 {
+		fired <- c.now
+	} else {
+		c.timers = append(c.timers, manualTimer{due: c.now.Add(d), fired: fired})
+	}
+	c.afters++
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return fired
+}
+
+// Advance moves the time forward by d and fires every timer that falls due
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.due.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.fired <- timer.due
+	}
+	c.timers = pending
+}
+
+// Elapsed returns the time passed since the clock was created
+func (c *manualClock) Elapsed() time.Duration {
+	return c.Now().Sub(time.Unix(0, 0))
+}
+
+// WaitForAfters blocks until After has been called n times in total, so the
+// code under test has set its timers before the test advances the clock
+func (c *manualClock) WaitForAfters(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		c.mu.Lock()
+		afters, changed := c.afters, c.changed
+		c.mu.Unlock()
+		if afters >= n {
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			t.Fatalf("After was called %d times, want %d", afters, n)
+		}
+	}
+}
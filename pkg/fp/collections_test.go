@@ -0,0 +1,85 @@
+package fp
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// The lazy stream operators must agree with their slice counterparts
+
+var sharedInputs = [][]int{
+	{},
+	{1},
+	{2, 4},
+	{1, 2, 3, 4, 5, 6, 7},
+	{2, 4, 6, 1, 8, 3},
+}
+
+var sharedPredicates = map[string]Predicate[int]{
+	"even":   func(x int) bool { return x%2 == 0 },
+	"always": func(int) bool { return true },
+	"never":  func(int) bool { return false },
+}
+
+// sameChunks compares slices of slices, treating nil and empty as equal
+func sameChunks[T comparable](a, b [][]T) bool {
+	return slices.EqualFunc(a, b, slices.Equal[[]T])
+}
+
+func TestTakeWhileMatchesSlices(t *testing.T) {
+	for _, input := range sharedInputs {
+		for name, predicate := range sharedPredicates {
+			want := TakeWhile(input, predicate)
+			if got := NewStream(input).TakeWhile(predicate).Collect(); !slices.Equal(got, want) {
+				t.Errorf("TakeWhile(%v, %s): stream %v, slice %v", input, name, got, want)
+			}
+		}
+	}
+}
+
+func TestDropWhileMatchesSlices(t *testing.T) {
+	for _, input := range sharedInputs {
+		for name, predicate := range sharedPredicates {
+			want := DropWhile(input, predicate)
+			if got := NewStream(input).DropWhile(predicate).Collect(); !slices.Equal(got, want) {
+				t.Errorf("DropWhile(%v, %s): stream %v, slice %v", input, name, got, want)
+			}
+		}
+	}
+}
+
+func TestIntersperseMatchesSlices(t *testing.T) {
+	for _, input := range sharedInputs {
+		want := Intersperse(input, 0)
+		if got := NewStream(input).Intersperse(0).Collect(); !slices.Equal(got, want) {
+			t.Errorf("Intersperse(%v): stream %v, slice %v", input, got, want)
+		}
+	}
+}
+
+func TestChunkMatchesSlices(t *testing.T) {
+	for _, input := range sharedInputs {
+		for size := -1; size <= len(input)+1; size++ {
+			t.Run(fmt.Sprint(input, size), func(t *testing.T) {
+				want := Chunk(input, size)
+				if got := StreamChunk(NewStream(input), size).Collect(); !sameChunks(got, want) {
+					t.Errorf("stream %v, slice %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestSlidingMatchesSlices(t *testing.T) {
+	for _, input := range sharedInputs {
+		for size := -1; size <= len(input)+1; size++ {
+			t.Run(fmt.Sprint(input, size), func(t *testing.T) {
+				want := Sliding(input, size)
+				if got := StreamSliding(NewStream(input), size).Collect(); !sameChunks(got, want) {
+					t.Errorf("stream %v, slice %v", got, want)
+				}
+			})
+		}
+	}
+}
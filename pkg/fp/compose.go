@@ -1,5 +1,10 @@
 package fp
 
+import (
+	"sync"
+	"time"
+)
+
 // Pipe executes function composition from left to right
 func Pipe[T any](value T, functions ...func(T) T) T {
 	result := value
@@ -127,7 +132,7 @@ func Memoize[T comparable, R any](fn func(T) R) func(T) R {
 	}
 }
 
-// MemoizeWithTTL caches the results of a function with TTL
+// MemoizeWithTTL caches the results of a function with TTL in milliseconds
 func MemoizeWithTTL[T comparable, R any](fn func(T) R, ttl int64) func(T) R {
 	type cacheEntry struct {
 		value     R
@@ -137,7 +142,7 @@ func MemoizeWithTTL[T comparable, R any](fn func(T) R, ttl int64) func(T) R {
 	cache := make(map[T]cacheEntry)
 
 	return func(t T) R {
-		now := getCurrentTimestamp() // Вы можете заменить на time.Now().Unix()
+		now := getCurrentTimestamp()
 
 		if entry, exists := cache[t]; exists && (now-entry.timestamp) < ttl {
 			return entry.value
@@ -152,40 +157,71 @@ func MemoizeWithTTL[T comparable, R any](fn func(T) R, ttl int64) func(T) R {
 	}
 }
 
-// Debounce creates a function with execution delay
+// Debounce creates a function that calls fn with the latest argument
+// once no call has been made for delay milliseconds
 func Debounce[T any](fn func(T), delay int64) func(T) {
-	var lastCall int64
+	return debounce(fn, time.Duration(delay)*time.Millisecond, SystemClock())
+}
+
+// debounce is Debounce driven by the clock. A single goroutine waits at a
+// time, and it waits again for as long as new calls move the deadline.
+func debounce[T any](fn func(T), delay time.Duration, clock Clock) func(T) {
+	var mu sync.Mutex
+	var latest T
+	var due time.Time
+	waiting := false
+
+	wait := func() {
+		mu.Lock()
+		for remaining := due.Sub(clock.Now()); remaining > 0; remaining = due.Sub(clock.Now()) {
+			mu.Unlock()
+			<-clock.After(remaining)
+			mu.Lock()
+		}
+		waiting = false
+		t := latest
+		mu.Unlock()
+		fn(t)
+	}
 
 	return func(t T) {
-		now := getCurrentTimestamp()
-		lastCall = now
-
-		go func() {
-			// Simple debounce implementation without time.Sleep for example
-			// In real code use time.Sleep(time.Duration(delay) * time.Millisecond)
-			if getCurrentTimestamp()-lastCall >= delay {
-				fn(t)
-			}
-		}()
+		mu.Lock()
+		defer mu.Unlock()
+		latest = t
+		due = clock.Now().Add(delay)
+		if !waiting {
+			waiting = true
+			go wait()
+		}
 	}
 }
 
-// Throttle creates a function with execution throttling
+// Throttle creates a function that calls fn at most once per interval milliseconds
 func Throttle[T any](fn func(T), interval int64) func(T) {
-	var lastExecution int64
+	return throttle(fn, time.Duration(interval)*time.Millisecond, SystemClock())
+}
+
+// throttle is Throttle driven by the clock
+func throttle[T any](fn func(T), interval time.Duration, clock Clock) func(T) {
+	var mu sync.Mutex
+	var lastExecution time.Time
+	executed := false
 
 	return func(t T) {
-		now := getCurrentTimestamp()
-		if now-lastExecution >= interval {
-			lastExecution = now
-			fn(t)
+		mu.Lock()
+		now := clock.Now()
+		if executed && now.Sub(lastExecution) < interval {
+			mu.Unlock()
+			return
 		}
+		executed = true
+		lastExecution = now
+		mu.Unlock()
+		fn(t)
 	}
 }
 
-// getCurrentTimestamp - helper function to get the current timestamp
-// In real code replace with time.Now().Unix()
+// getCurrentTimestamp returns the current Unix time in milliseconds
 func getCurrentTimestamp() int64 {
-	// Stub for compilation, in real code use time.Now().Unix()
-	return 0
+	return time.Now().UnixMilli()
 }
@@ -0,0 +1,50 @@
+package fp
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDebounceFunc(t *testing.T) {
+	clock := newManualClock()
+	calls := make(chan int, 10)
+	debounced := debounce(func(x int) { calls <- x }, 10*time.Millisecond, clock)
+
+	debounced(1)
+	clock.WaitForAfters(t, 1)
+	clock.Advance(5 * time.Millisecond)
+	debounced(2)
+
+	// The first wait ends before the deadline moved by the second call
+	clock.Advance(5 * time.Millisecond)
+	clock.WaitForAfters(t, 2)
+	if len(calls) != 0 {
+		t.Fatalf("fn was called with %d before the quiet period", <-calls)
+	}
+	clock.Advance(5 * time.Millisecond)
+	if got := <-calls; got != 2 {
+		t.Fatalf("fn was called with %d, want 2", got)
+	}
+
+	debounced(3)
+	clock.WaitForAfters(t, 3)
+	clock.Advance(10 * time.Millisecond)
+	if got := <-calls; got != 3 || len(calls) != 0 {
+		t.Fatalf("fn was called with %d, want only 3", got)
+	}
+}
+
+func TestThrottleFunc(t *testing.T) {
+	clock := newManualClock()
+	var calls []int
+	throttled := throttle(func(x int) { calls = append(calls, x) }, 10*time.Millisecond, clock)
+
+	for i := range 10 {
+		throttled(i)
+		clock.Advance(4 * time.Millisecond)
+	}
+	if want := []int{0, 3, 6, 9}; !slices.Equal(calls, want) {
+		t.Fatalf("got %v, want %v", calls, want)
+	}
+}
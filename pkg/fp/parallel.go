@@ -2,15 +2,25 @@ package fp
 
 import (
 	"context"
+	"errors"
+	"math"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // ParallelConfig configuration for parallel processing
 type ParallelConfig struct {
+	// WorkerCount is the number of goroutines sharing the work
 	WorkerCount int
-	BufferSize  int
+	// BufferSize is the capacity of the job and result channels of FilterParallel.
+	// MapParallelWithConfig, ForEachParallel and pipelines hand out chunks
+	// instead and do not use it.
+	BufferSize int
+	// Grain is the number of consecutive items a worker takes at once.
+	// Zero picks it from the input size so that every worker gets several chunks.
+	Grain int
 }
 
 // DefaultParallelConfig returns the default configuration
@@ -21,40 +31,19 @@ func DefaultParallelConfig() ParallelConfig {
 	}
 }
 
-// MapParallelWithConfig parallel Map with configuration
+// MapParallelWithConfig parallel Map with configuration.
+// Inputs too cheap to map in parallel are mapped on the calling goroutine.
 func MapParallelWithConfig[T, R any](slice []T, mapper Mapper[T, R], config ParallelConfig) []R {
 	if slice == nil || len(slice) == 0 {
 		return nil
 	}
 
-	if len(slice) < config.WorkerCount {
-		return Map(slice, mapper)
-	}
-
 	result := make([]R, len(slice))
-	jobs := make(chan int, config.BufferSize)
-	var wg sync.WaitGroup
-
-	// Start workers
-	for i := 0; i < config.WorkerCount; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for idx := range jobs {
-				result[idx] = mapper(slice[idx])
-			}
-		}()
-	}
-
-	// Send jobs
-	go func() {
-		defer close(jobs)
-		for i := range slice {
-			jobs <- i
+	forEachRange(len(slice), config, SystemClock(), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			result[i] = mapper(slice[i])
 		}
-	}()
-
-	wg.Wait()
+	})
 	return result
 }
 
@@ -173,149 +162,111 @@ func ReduceParallel[T any](slice []T, reducer func(T, T) T, identity T, config P
 	return finalResult
 }
 
-// Pipeline represents a data processing pipeline
-type Pipeline[T any] struct {
-	data   []T
-	config ParallelConfig
-}
-
-// NewPipeline creates a new pipeline
-func NewPipeline[T any](data []T) *Pipeline[T] {
-	return &Pipeline[T]{
-		data:   data,
-		config: DefaultParallelConfig(),
+// chunkSize returns the number of consecutive items a worker takes at once
+func chunkSize(n int, config ParallelConfig) int {
+	if config.Grain > 0 {
+		return config.Grain
 	}
+	return max(1, n/(max(config.WorkerCount, 1)*4))
 }
 
-// WithConfig sets the configuration
-func (p *Pipeline[T]) WithConfig(config ParallelConfig) *Pipeline[T] {
-	p.config = config
-	return p
-}
-
-// Map applies a transformation function
-func (p *Pipeline[T]) Map(mapper Mapper[T, T]) *Pipeline[T] {
-	p.data = MapParallelWithConfig(p.data, mapper, p.config)
-	return p
-}
-
-// Filter applies a filtering function
-func (p *Pipeline[T]) Filter(predicate Predicate[T]) *Pipeline[T] {
-	p.data = FilterParallel(p.data, predicate, p.config)
-	return p
-}
-
-// Reduce reduces the data
-func (p *Pipeline[T]) Reduce(reducer func(T, T) T, identity T) T {
-	return ReduceParallel(p.data, reducer, identity, p.config)
-}
-
-// Collect returns the result
-func (p *Pipeline[T]) Collect() []T {
-	return p.data
-}
+const (
+	// probeItems is the number of items processed on the calling goroutine to estimate the cost of an item
+	probeItems = 16
+	// minWorkerTime is the least estimated work per worker that pays for starting it
+	minWorkerTime = 50 * time.Microsecond
+)
 
-// ForEachParallel executes a function for each element in parallel
-func ForEachParallel[T any](slice []T, action func(T), config ParallelConfig) {
-	if slice == nil || len(slice) == 0 {
-		return
+// forEachRange splits [0, n) into chunks of consecutive indexes and
+// distributes them between the workers. The first items are processed on the
+// calling goroutine to estimate the cost of the rest, which also runs there
+// when it is too cheap to split between the workers.
+// The probe is timed with the clock, and forEachRange reports whether the work was split.
+func forEachRange(n int, config ParallelConfig, clock Clock, process func(lo, hi int)) bool {
+	size := chunkSize(n, config)
+	if config.WorkerCount <= 1 || n <= size {
+		process(0, n)
+		return false
 	}
 
-	jobs := make(chan T, config.BufferSize)
-	var wg sync.WaitGroup
-
-	// Start workers
-	for i := 0; i < config.WorkerCount; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for item := range jobs {
-				action(item)
-			}
-		}()
+	// The probe is timed in two halves and the faster one counts, so a single
+	// preemption or GC pause does not make cheap items look expensive
+	probed := min(probeItems, size)
+	half := max(probed/2, 1)
+	perItem := math.Inf(1)
+	for lo := 0; lo < probed; lo += half {
+		hi := min(lo+half, probed)
+		started := clock.Now()
+		process(lo, hi)
+		perItem = min(perItem, float64(clock.Now().Sub(started))/float64(hi-lo))
 	}
 
-	// Send jobs
-	go func() {
-		defer close(jobs)
-		for _, item := range slice {
-			jobs <- item
-		}
-	}()
+	rest := n - probed
+	estimate := time.Duration(perItem * float64(rest))
+	if estimate < time.Duration(config.WorkerCount)*minWorkerTime {
+		process(probed, n)
+		return false
+	}
 
-	wg.Wait()
+	forEachChunk(rest, size, config.WorkerCount, func(_, lo, hi int) {
+		process(probed+lo, probed+hi)
+	})
+	return true
 }
 
-// MapWithContext executes a function for each element in parallel with a context
-func MapWithContext[T, R any](ctx context.Context, slice []T, mapper func(context.Context, T) (R, error), config ParallelConfig) ([]R, error) {
-	if slice == nil || len(slice) == 0 {
-		return nil, nil
-	}
-
-	result := make([]R, len(slice))
-	jobs := make(chan int, config.BufferSize)
-	errors := make(chan error, len(slice))
+// forEachChunk splits [0, n) into chunks of the given size and calls process
+// for every chunk on up to workerCount goroutines
+func forEachChunk(n, size, workerCount int, process func(chunk, lo, hi int)) {
+	chunks := (n + size - 1) / size
+	var next atomic.Int64
 	var wg sync.WaitGroup
 
-	// Start workers
-	for i := 0; i < config.WorkerCount; i++ {
+	for i := 0; i < min(workerCount, chunks); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for {
-				select {
-				case idx, ok := <-jobs:
-					if !ok {
-						return
-					}
-
-					res, err := mapper(ctx, slice[idx])
-					if err != nil {
-						errors <- err
-						return
-					}
-					result[idx] = res
-
-				case <-ctx.Done():
-					errors <- ctx.Err()
+				chunk := int(next.Add(1) - 1)
+				if chunk >= chunks {
 					return
 				}
+				lo := chunk * size
+				process(chunk, lo, min(lo+size, n))
 			}
 		}()
 	}
+	wg.Wait()
+}
 
-	// Send jobs
-	go func() {
-		defer close(jobs)
-		for i := range slice {
-			select {
-			case jobs <- i:
-			case <-ctx.Done():
-				return
-			}
+// ForEachParallel executes a function for each element in parallel.
+// Inputs too cheap to process in parallel are processed on the calling goroutine.
+func ForEachParallel[T any](slice []T, action func(T), config ParallelConfig) {
+	if slice == nil || len(slice) == 0 {
+		return
+	}
+
+	forEachRange(len(slice), config, SystemClock(), func(lo, hi int) {
+		for _, item := range slice[lo:hi] {
+			action(item)
 		}
-	}()
+	})
+}
 
-	// Wait for completion or error
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+// MapWithContext executes a function for each element in parallel with a context.
+// The first error cancels the context passed to the other calls and is returned
+// as the mapper returned it, or ctx.Err() if the context was cancelled.
+// Use TryPipelineMap to know the index of the failed item.
+func MapWithContext[T, R any](ctx context.Context, slice []T, mapper func(context.Context, T) (R, error), config ParallelConfig) ([]R, error) {
+	result, err := TryPipelineMap(NewPipelineCtx(ctx, slice).WithConfig(config), mapper).Collect()
+	if err == nil {
+		return result, nil
+	}
 
-	select {
-	case <-done:
-		select {
-		case err := <-errors:
-			return nil, err
-		default:
-			return result, nil
-		}
-	case err := <-errors:
-		return nil, err
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	var failed *IndexedError
+	if errors.As(err, &failed) {
+		return nil, failed.Err
 	}
+	return nil, ctx.Err()
 }
 
 // BatchProcessor processes data in batches
@@ -0,0 +1,228 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapWithContext(t *testing.T) {
+	config := ParallelConfig{WorkerCount: 4, BufferSize: 10}
+	got, err := MapWithContext(context.Background(), []int{1, 2, 3}, func(_ context.Context, x int) (int, error) {
+		return x * 2, nil
+	}, config)
+	if err != nil || !slices.Equal(got, []int{2, 4, 6}) {
+		t.Fatalf("got %v and %v", got, err)
+	}
+}
+
+func TestMapWithContextReturnsTheMapperError(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	config := ParallelConfig{WorkerCount: 4, BufferSize: 10}
+	got, err := MapWithContext(context.Background(), RangeStream(0, 100).Collect(), func(_ context.Context, x int) (int, error) {
+		if x == 50 {
+			return 0, sentinel
+		}
+		return x, nil
+	}, config)
+	if err != sentinel || got != nil {
+		t.Fatalf("got %v and %v, want the sentinel error", got, err)
+	}
+}
+
+func TestMapWithContextReturnsTheContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	config := ParallelConfig{WorkerCount: 4, BufferSize: 10}
+	_, err := MapWithContext(ctx, []int{1, 2, 3}, func(_ context.Context, x int) (int, error) {
+		return x, nil
+	}, config)
+	if err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestMapParallelWithConfigMatchesMap(t *testing.T) {
+	configs := []ParallelConfig{
+		{WorkerCount: 0},
+		{WorkerCount: 1},
+		{WorkerCount: 4},
+		{WorkerCount: 4, Grain: 1},
+		{WorkerCount: 4, Grain: 7},
+		{WorkerCount: 4, Grain: 1 << 20},
+	}
+	for _, n := range []int{0, 1, 5, 17, 1000, 100_003} {
+		data := RangeStream(0, n).Collect()
+		want := Map(data, benchDouble)
+		for _, config := range configs {
+			if got := MapParallelWithConfig(data, benchDouble, config); !slices.Equal(got, want) {
+				t.Fatalf("%d items with %+v: results differ from Map", n, config)
+			}
+
+			var sum atomic.Int64
+			ForEachParallel(data, func(x int) { sum.Add(int64(x)) }, config)
+			if sum.Load() != int64(Sum(data)) {
+				t.Fatalf("%d items with %+v: ForEachParallel missed items", n, config)
+			}
+		}
+	}
+}
+
+// processedOnce returns a process function for forEachRange that counts the
+// calls for every index, and a check that every index was processed once
+func processedOnce(t *testing.T, n int, cost func()) (func(lo, hi int), func()) {
+	counts := make([]atomic.Int64, n)
+	process := func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			cost()
+			counts[i].Add(1)
+		}
+	}
+	check := func() {
+		t.Helper()
+		for i := range counts {
+			if got := counts[i].Load(); got != 1 {
+				t.Fatalf("index %d was processed %d times", i, got)
+			}
+		}
+	}
+	return process, check
+}
+
+// costing returns a cost function that advances the clock by d for every item,
+// so the probe of forEachRange measures exactly d whatever the machine is doing
+func costing(clock *manualClock, d time.Duration) func() {
+	return func() { clock.Advance(d) }
+}
+
+func TestForEachRangeFallsBackToSequential(t *testing.T) {
+	clock := newManualClock()
+	process, check := processedOnce(t, 1000, costing(clock, 100*time.Nanosecond))
+	if forEachRange(1000, ParallelConfig{WorkerCount: 4}, clock, process) {
+		t.Fatal("cheap items were split between workers")
+	}
+	check()
+}
+
+func TestForEachRangeSplitsCostlyWork(t *testing.T) {
+	clock := newManualClock()
+	process, check := processedOnce(t, 64, costing(clock, 10*time.Microsecond))
+	if !forEachRange(64, ParallelConfig{WorkerCount: 4}, clock, process) {
+		t.Fatal("costly items ran sequentially")
+	}
+	check()
+}
+
+func TestForEachRangeWithOneWorker(t *testing.T) {
+	clock := newManualClock()
+	process, check := processedOnce(t, 64, costing(clock, 10*time.Microsecond))
+	if forEachRange(64, ParallelConfig{WorkerCount: 1}, clock, process) {
+		t.Fatal("a single worker split the work")
+	}
+	check()
+}
+
+// channelMapParallel is MapParallelWithConfig before chunking, which handed
+// every index to the workers through a channel, kept as the benchmark baseline
+func channelMapParallel[T, R any](slice []T, mapper Mapper[T, R], config ParallelConfig) []R {
+	result := make([]R, len(slice))
+	jobs := make(chan int, config.BufferSize)
+	var wg sync.WaitGroup
+	for range config.WorkerCount {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result[i] = mapper(slice[i])
+			}
+		}()
+	}
+	for i := range slice {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return result
+}
+
+// costly burns roughly a microsecond per call
+func costly(x int) int {
+	for range 300 {
+		x = x*31 + 7
+	}
+	return x
+}
+
+// BenchmarkMapParallel compares Map, the per-item channel distribution and the
+// chunked one for cheap and costly mappers over small and large inputs
+func BenchmarkMapParallel(b *testing.B) {
+	config := ParallelConfig{WorkerCount: 4, BufferSize: 100}
+	mappers := []struct {
+		name   string
+		mapper Mapper[int, int]
+	}{
+		{"cheap", benchDouble},
+		{"costly", costly},
+	}
+
+	for _, m := range mappers {
+		for _, n := range []int{100, 10_000, 1_000_000} {
+			data := RangeStream(0, n).Collect()
+			b.Run(fmt.Sprintf("%s/%d/Map", m.name, n), func(b *testing.B) {
+				for b.Loop() {
+					Map(data, m.mapper)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/%d/channel", m.name, n), func(b *testing.B) {
+				for b.Loop() {
+					channelMapParallel(data, m.mapper, config)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/%d/chunked", m.name, n), func(b *testing.B) {
+				for b.Loop() {
+					MapParallelWithConfig(data, m.mapper, config)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkMapParallelGrain compares fixed grains with the adaptive one
+func BenchmarkMapParallelGrain(b *testing.B) {
+	data := RangeStream(0, 1_000_000).Collect()
+	for _, grain := range []int{0, 1, 64, 4096, 65536} {
+		config := ParallelConfig{WorkerCount: 4, Grain: grain}
+		b.Run(fmt.Sprintf("grain=%d", grain), func(b *testing.B) {
+			for b.Loop() {
+				MapParallelWithConfig(data, costly, config)
+			}
+		})
+	}
+}
+
+// BenchmarkForEachParallelFallback measures inputs around the point where the
+// heuristic switches from sequential to parallel execution
+func BenchmarkForEachParallelFallback(b *testing.B) {
+	config := ParallelConfig{WorkerCount: 4}
+	for _, n := range []int{16, 64, 256, 1024, 4096} {
+		data := RangeStream(0, n).Collect()
+		out := make([]int, n)
+		b.Run(fmt.Sprintf("%d/sequential", n), func(b *testing.B) {
+			for b.Loop() {
+				for _, x := range data {
+					out[x] = costly(x)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("%d/ForEachParallel", n), func(b *testing.B) {
+			for b.Loop() {
+				ForEachParallel(data, func(x int) { out[x] = costly(x) }, config)
+			}
+		})
+	}
+}
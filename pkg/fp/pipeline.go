@@ -0,0 +1,431 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline represents a data processing pipeline.
+//
+// Stages are recorded and run lazily by Collect and Reduce. The input is split
+// into chunks and every worker takes a chunk through all the stages in a single
+// pass, so there is no barrier and no intermediate slice between stages.
+type Pipeline[T any] struct {
+	config ParallelConfig
+	start  func(run *pipelineRun) (int, pipelineEmit[T])
+	plan   []pipelineStage
+	last   atomic.Pointer[pipelineRun]
+}
+
+// pipelineEmit pushes the input items in [lo, hi) through the stages of a run
+type pipelineEmit[T any] func(lo, hi int, yield func(T) bool)
+
+// pipelineStage describes a recorded stage
+type pipelineStage struct {
+	name    string
+	barrier bool
+	// config of the pass before a barrier
+	config ParallelConfig
+}
+
+// PipelineStageStats reports what a stage did in the last run of a pipeline.
+// Stages fused into one pass share the timings of the pass, since timing them
+// separately would cost a clock reading per item.
+type PipelineStageStats struct {
+	Stage    string
+	ItemsIn  int64
+	ItemsOut int64
+	// Wall is the elapsed time of the pass that ran the stage
+	Wall time.Duration
+	// Busy is the wall time the workers of the pass spent processing chunks,
+	// summed over the workers. It includes time a worker waited for a CPU.
+	Busy time.Duration
+	// Parallelism is Busy divided by Wall, the number of workers that were busy on average
+	Parallelism float64
+}
+
+// NewPipeline creates a new pipeline
+func NewPipeline[T any](data []T) *Pipeline[T] {
+	return &Pipeline[T]{
+		config: DefaultParallelConfig(),
+		start: func(run *pipelineRun) (int, pipelineEmit[T]) {
+			return len(data), sliceEmit(data, &run.stages[0].out)
+		},
+		plan: []pipelineStage{{name: "Source"}},
+	}
+}
+
+// sliceEmit pushes the items of a slice and counts them
+func sliceEmit[T any](data []T, out *atomic.Int64) pipelineEmit[T] {
+	return func(lo, hi int, yield func(T) bool) {
+		count := 0
+		defer func() { out.Add(int64(count)) }()
+		for _, item := range data[lo:hi] {
+			count++
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// WithConfig sets the configuration
+func (p *Pipeline[T]) WithConfig(config ParallelConfig) *Pipeline[T] {
+	p.config = config
+	return p
+}
+
+// Map applies a transformation function
+func (p *Pipeline[T]) Map(mapper Mapper[T, T]) *Pipeline[T] {
+	mapped := PipelineMap(p, mapper)
+	p.start, p.plan = mapped.start, mapped.plan
+	return p
+}
+
+// Filter applies a filtering function
+func (p *Pipeline[T]) Filter(predicate Predicate[T]) *Pipeline[T] {
+	filtered := fuse(p, "Filter", func(_ *pipelineRun, emit pipelineEmit[T], out *atomic.Int64) pipelineEmit[T] {
+		return func(lo, hi int, yield func(T) bool) {
+			count := 0
+			defer func() { out.Add(int64(count)) }()
+			emit(lo, hi, func(item T) bool {
+				if !predicate(item) {
+					return true
+				}
+				count++
+				return yield(item)
+			})
+		}
+	})
+	p.start, p.plan = filtered.start, filtered.plan
+	return p
+}
+
+// PipelineMap applies a transformation function that may change the element type
+func PipelineMap[T, R any](p *Pipeline[T], mapper Mapper[T, R]) *Pipeline[R] {
+	return fuse(p, "Map", func(_ *pipelineRun, emit pipelineEmit[T], out *atomic.Int64) pipelineEmit[R] {
+		return func(lo, hi int, yield func(R) bool) {
+			count := 0
+			defer func() { out.Add(int64(count)) }()
+			emit(lo, hi, func(item T) bool {
+				count++
+				return yield(mapper(item))
+			})
+		}
+	})
+}
+
+// PipelineFlatMap applies a function and flattens the results into the pipeline
+func PipelineFlatMap[T, R any](p *Pipeline[T], mapper func(T) []R) *Pipeline[R] {
+	return fuse(p, "FlatMap", func(_ *pipelineRun, emit pipelineEmit[T], out *atomic.Int64) pipelineEmit[R] {
+		return func(lo, hi int, yield func(R) bool) {
+			count := 0
+			defer func() { out.Add(int64(count)) }()
+			emit(lo, hi, func(item T) bool {
+				for _, mapped := range mapper(item) {
+					count++
+					if !yield(mapped) {
+						return false
+					}
+				}
+				return true
+			})
+		}
+	})
+}
+
+// PipelineDistinct removes duplicates, keeping the first occurrence.
+// The previous stages run to completion before the following ones start.
+func PipelineDistinct[T comparable](p *Pipeline[T]) *Pipeline[T] {
+	return barrier(p, "Distinct", Unique[T])
+}
+
+// PipelineDistinctBy removes duplicates by key, keeping the first occurrence.
+// The previous stages run to completion before the following ones start.
+func PipelineDistinctBy[T any, K comparable](p *Pipeline[T], keyExtractor KeyExtractor[T, K]) *Pipeline[T] {
+	return barrier(p, "DistinctBy", func(items []T) []T {
+		return UniqueBy(items, keyExtractor)
+	})
+}
+
+// PipelineGroupBy groups the elements by key into pairs of the key and its elements,
+// in the order the keys first appear.
+// The previous stages run to completion before the following ones start.
+func PipelineGroupBy[T any, K comparable](p *Pipeline[T], keyExtractor KeyExtractor[T, K]) *Pipeline[Pair[K, []T]] {
+	return barrier(p, "GroupBy", func(items []T) []Pair[K, []T] {
+		index := make(map[K]int)
+		var groups []Pair[K, []T]
+		for _, item := range items {
+			key := keyExtractor(item)
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, Pair[K, []T]{First: key})
+			}
+			groups[i].Second = append(groups[i].Second, item)
+		}
+		return groups
+	})
+}
+
+// fuse attaches a stage that runs in the same pass as the previous ones.
+// The stage adds the number of items it emits to out.
+func fuse[T, R any](p *Pipeline[T], name string, stage func(run *pipelineRun, emit pipelineEmit[T], out *atomic.Int64) pipelineEmit[R]) *Pipeline[R] {
+	start := p.start
+	index := len(p.plan)
+	return &Pipeline[R]{
+		config: p.config,
+		start: func(run *pipelineRun) (int, pipelineEmit[R]) {
+			n, emit := start(run)
+			return n, stage(run, emit, &run.stages[index].out)
+		},
+		plan: append(slices.Clip(p.plan), pipelineStage{name: name}),
+	}
+}
+
+// barrier attaches a stage that needs all the items at once. The previous stages
+// run to completion first, and the output of the stage is the input of the
+// stages that follow.
+func barrier[T, R any](p *Pipeline[T], name string, stage func([]T) []R) *Pipeline[R] {
+	upstream := &Pipeline[T]{config: p.config, start: p.start, plan: p.plan}
+	index := len(p.plan)
+	return &Pipeline[R]{
+		config: p.config,
+		start: func(run *pipelineRun) (int, pipelineEmit[R]) {
+			input := upstream.collect(run)
+			started := time.Now()
+			items := stage(input)
+			elapsed := time.Since(started)
+			run.stages[index].wall, run.stages[index].busy = elapsed, elapsed
+			return len(items), sliceEmit(items, &run.stages[index].out)
+		},
+		plan: append(slices.Clip(p.plan), pipelineStage{name: name, barrier: true, config: p.config}),
+	}
+}
+
+// Reduce runs the pipeline and reduces the data.
+// Chunks are reduced in parallel, so the reducer must be associative.
+func (p *Pipeline[T]) Reduce(reducer func(T, T) T, identity T) T {
+	run := newPipelineRun(context.Background(), FailFast, nil, len(p.plan))
+	defer run.close()
+	defer p.last.Store(run)
+	return p.reduce(run, reducer, identity)
+}
+
+// reduce reduces the output of a run
+func (p *Pipeline[T]) reduce(run *pipelineRun, reducer func(T, T) T, identity T) T {
+	n, emit := p.start(run)
+	size, chunks := p.chunking(n)
+	partials := make([]T, chunks)
+	p.pass(run, n, size, chunks, func(chunk, lo, hi int) {
+		result := identity
+		emit(lo, hi, func(item T) bool {
+			result = reducer(result, item)
+			return true
+		})
+		partials[chunk] = result
+	})
+
+	if chunks == 1 {
+		return partials[0]
+	}
+	result := identity
+	for _, partial := range partials {
+		result = reducer(result, partial)
+	}
+	return result
+}
+
+// Collect runs the pipeline and returns the result
+func (p *Pipeline[T]) Collect() []T {
+	run := newPipelineRun(context.Background(), FailFast, nil, len(p.plan))
+	defer run.close()
+	defer p.last.Store(run)
+	return p.collect(run)
+}
+
+// collect returns the output of a run
+func (p *Pipeline[T]) collect(run *pipelineRun) []T {
+	n, emit := p.start(run)
+	size, chunks := p.chunking(n)
+	parts := make([][]T, chunks)
+	p.pass(run, n, size, chunks, func(chunk, lo, hi int) {
+		result := make([]T, 0, hi-lo)
+		emit(lo, hi, func(item T) bool {
+			result = append(result, item)
+			return true
+		})
+		parts[chunk] = result
+	})
+	return slices.Concat(parts...)
+}
+
+// chunking returns the chunk size and the number of chunks for n items.
+// Inputs too small to split between workers are processed as a single chunk.
+func (p *Pipeline[T]) chunking(n int) (int, int) {
+	if p.config.WorkerCount <= 1 || n < p.config.WorkerCount {
+		return max(n, 1), 1
+	}
+	size := chunkSize(n, p.config)
+	return size, (n + size - 1) / size
+}
+
+// pass processes the chunks of [0, n) and records the timings of the stages
+// that run in the pass
+func (p *Pipeline[T]) pass(run *pipelineRun, n, size, chunks int, process func(chunk, lo, hi int)) {
+	var busy atomic.Int64
+	timed := func(chunk, lo, hi int) {
+		started := time.Now()
+		process(chunk, lo, hi)
+		busy.Add(int64(time.Since(started)))
+	}
+
+	started := time.Now()
+	if chunks == 1 {
+		timed(0, 0, n)
+	} else {
+		forEachChunk(n, size, p.config.WorkerCount, timed)
+	}
+	wall, total := time.Since(started), time.Duration(busy.Load())
+
+	for i := len(p.plan) - 1; i >= 0 && !p.plan[i].barrier; i-- {
+		run.stages[i].wall, run.stages[i].busy = wall, total
+	}
+}
+
+// Explain describes the recorded stages, one per line, with the way they run
+func (p *Pipeline[T]) Explain() string {
+	lines := make([]string, len(p.plan))
+	config := p.config
+	for i := len(p.plan) - 1; i >= 0; i-- {
+		stage := p.plan[i]
+		mode := "fused, sequential"
+		switch {
+		case stage.barrier:
+			mode = "barrier, sequential"
+			config = stage.config
+		case config.WorkerCount > 1:
+			mode = fmt.Sprintf("fused, %d workers", config.WorkerCount)
+			if config.Grain > 0 {
+				mode += fmt.Sprintf(", grain %d", config.Grain)
+			}
+		}
+		lines[i] = fmt.Sprintf("%d. %s: %s", i+1, stage.name, mode)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Stats reports every stage of the last run of the pipeline, nil before the first run
+func (p *Pipeline[T]) Stats() []PipelineStageStats {
+	run := p.last.Load()
+	if run == nil {
+		return nil
+	}
+
+	stats := make([]PipelineStageStats, min(len(p.plan), len(run.stages)))
+	for i := range stats {
+		stage := &run.stages[i]
+		stats[i] = PipelineStageStats{
+			Stage:    p.plan[i].name,
+			ItemsOut: stage.out.Load(),
+			Wall:     stage.wall,
+			Busy:     stage.busy,
+		}
+		if i == 0 {
+			stats[i].ItemsIn = stats[i].ItemsOut
+		} else {
+			stats[i].ItemsIn = stats[i-1].ItemsOut
+		}
+		if stage.wall > 0 {
+			stats[i].Parallelism = float64(stage.busy) / float64(stage.wall)
+		}
+	}
+	return stats
+}
+
+// pipelineRun is the state of a single execution of a pipeline
+type pipelineRun struct {
+	stages  []stageCounters
+	ctx     context.Context
+	cancel  context.CancelFunc
+	release func() bool
+	policy  ErrorPolicy
+	sink    func(error)
+	// stopped is set once the run must not take new items
+	stopped atomic.Bool
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// stageCounters are the measurements of a stage in a run
+type stageCounters struct {
+	out  atomic.Int64
+	wall time.Duration
+	busy time.Duration
+}
+
+// newPipelineRun starts a run that stops when the context is cancelled
+func newPipelineRun(ctx context.Context, policy ErrorPolicy, sink func(error), stages int) *pipelineRun {
+	run := &pipelineRun{stages: make([]stageCounters, stages), policy: policy, sink: sink}
+	run.ctx, run.cancel = context.WithCancel(ctx)
+	run.release = context.AfterFunc(run.ctx, func() {
+		run.stopped.Store(true)
+	})
+	return run
+}
+
+// close releases the resources of the run
+func (run *pipelineRun) close() {
+	run.release()
+	run.cancel()
+}
+
+// fail records the error of the item with the given index and reports
+// whether the run should go on
+func (run *pipelineRun) fail(index int, err error) bool {
+	err = &IndexedError{Index: index, Err: err}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	switch run.policy {
+	case FailFast:
+		if run.stopped.Swap(true) {
+			return false
+		}
+		run.errs = append(run.errs, err)
+		run.cancel()
+		return false
+	case SkipErrors:
+		run.errs = append(run.errs, err)
+	case DeadLetter:
+		if run.sink != nil {
+			run.sink(err)
+		}
+	}
+	return true
+}
+
+// err returns the errors of the run ordered by item index, and the error
+// of the parent context if it was cancelled
+func (run *pipelineRun) err(parent context.Context) error {
+	run.mu.Lock()
+	errs := slices.Clone(run.errs)
+	run.mu.Unlock()
+
+	slices.SortStableFunc(errs, func(a, b error) int {
+		return a.(*IndexedError).Index - b.(*IndexedError).Index
+	})
+	if err := parent.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,120 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// eagerPipeline runs Filter, Map, Filter and Map the way Pipeline did before
+// its stages were fused: every stage is a parallel pass that materializes a slice
+func eagerPipeline(data []int, config ParallelConfig) []int {
+	data = FilterParallel(data, benchNotFifth, config)
+	data = MapParallelWithConfig(data, benchDouble, config)
+	data = FilterParallel(data, func(x int) bool { return x%3 != 0 }, config)
+	return MapParallelWithConfig(data, benchIncrement, config)
+}
+
+// fusedPipeline runs the same stages as eagerPipeline in a single pass per chunk
+func fusedPipeline(data []int, config ParallelConfig) *Pipeline[int] {
+	return NewPipeline(data).
+		WithConfig(config).
+		Filter(benchNotFifth).
+		Map(benchDouble).
+		Filter(func(x int) bool { return x%3 != 0 }).
+		Map(benchIncrement)
+}
+
+func TestPipelineMatchesEagerStages(t *testing.T) {
+	data := RangeStream(0, 10_000).Collect()
+	for _, workers := range []int{1, 4} {
+		config := ParallelConfig{WorkerCount: workers, BufferSize: 100}
+		want := eagerPipeline(data, config)
+		if got := fusedPipeline(data, config).Collect(); !slices.Equal(got, want) {
+			t.Fatalf("%d workers: fused and eager pipelines differ", workers)
+		}
+		sum := fusedPipeline(data, config).Reduce(func(a, b int) int { return a + b }, 0)
+		if want := Sum(want); sum != want {
+			t.Fatalf("%d workers: reduced to %d, want %d", workers, sum, want)
+		}
+	}
+}
+
+func TestTryPipelineIndexedErrors(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	got, err := TryPipelineMap(
+		NewPipelineCtx(context.Background(), RangeStream(0, 100).Collect()).
+			WithConfig(ParallelConfig{WorkerCount: 4}).
+			WithPolicy(SkipErrors),
+		func(_ context.Context, x int) (int, error) {
+			if x%30 == 0 {
+				return 0, sentinel
+			}
+			return x, nil
+		},
+	).Collect()
+
+	if len(got) != 96 || !errors.Is(err, sentinel) {
+		t.Fatalf("got %d items and %v", len(got), err)
+	}
+	var indexes []int
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var failed *IndexedError
+		if !errors.As(err, &failed) {
+			t.Fatalf("got %v, want an IndexedError", err)
+		}
+		indexes = append(indexes, failed.Index)
+	}
+	if want := []int{0, 30, 60, 90}; !slices.Equal(indexes, want) {
+		t.Fatalf("got failed indexes %v, want %v", indexes, want)
+	}
+}
+
+func TestPipelineExplainAndStats(t *testing.T) {
+	p := PipelineDistinct(PipelineMap(
+		NewPipeline(RangeStream(0, 1000).Collect()).
+			WithConfig(ParallelConfig{WorkerCount: 4, Grain: 50}).
+			Filter(func(x int) bool { return x%2 == 0 }),
+		func(x int) int { return x % 10 },
+	))
+
+	want := "1. Source: fused, 4 workers, grain 50\n" +
+		"2. Filter: fused, 4 workers, grain 50\n" +
+		"3. Map: fused, 4 workers, grain 50\n" +
+		"4. Distinct: barrier, sequential"
+	if got := p.Explain(); got != want {
+		t.Fatalf("got plan\n%s\nwant\n%s", got, want)
+	}
+	if p.Stats() != nil {
+		t.Fatal("got stats before the first run")
+	}
+
+	p.Collect()
+	items := [][2]int64{{1000, 1000}, {1000, 500}, {500, 500}, {500, 5}}
+	for i, stats := range p.Stats() {
+		if [2]int64{stats.ItemsIn, stats.ItemsOut} != items[i] {
+			t.Fatalf("stage %s: got %d in and %d out, want %v", stats.Stage, stats.ItemsIn, stats.ItemsOut, items[i])
+		}
+		if stats.Wall <= 0 || stats.Busy <= 0 || stats.Parallelism <= 0 {
+			t.Fatalf("stage %s: got %+v", stats.Stage, stats)
+		}
+	}
+}
+
+// BenchmarkPipeline compares the fused pipeline with eager per-stage passes on 10M ints
+func BenchmarkPipeline(b *testing.B) {
+	data := RangeStream(0, 10_000_000).Collect()
+	config := ParallelConfig{WorkerCount: 4, BufferSize: 100}
+
+	b.Run("fused", func(b *testing.B) {
+		for b.Loop() {
+			fusedPipeline(data, config).Collect()
+		}
+	})
+	b.Run("eager", func(b *testing.B) {
+		for b.Loop() {
+			eagerPipeline(data, config)
+		}
+	})
+}
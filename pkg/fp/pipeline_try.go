@@ -0,0 +1,169 @@
+package fp
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// IndexedError describes an item that failed in a TryPipeline stage
+type IndexedError struct {
+	// Index of the item in the pipeline input
+	Index int
+	Err   error
+}
+
+// Error returns the error message
+func (e *IndexedError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *IndexedError) Unwrap() error {
+	return e.Err
+}
+
+// TryPipeline is a pipeline whose stages can fail and that stops when its context is cancelled.
+// With the FailFast policy the first error cancels the context passed to the
+// stages and the workers stop taking new items.
+type TryPipeline[T any] struct {
+	ctx      context.Context
+	pipeline *Pipeline[indexed[T]]
+	policy   ErrorPolicy
+	sink     func(error)
+}
+
+// indexed is an item together with the index of the input item it came from
+type indexed[T any] struct {
+	index int
+	value T
+}
+
+// NewPipelineCtx creates a new pipeline bound to the context with the FailFast policy
+func NewPipelineCtx[T any](ctx context.Context, data []T) *TryPipeline[T] {
+	return &TryPipeline[T]{
+		ctx: ctx,
+		pipeline: &Pipeline[indexed[T]]{
+			config: DefaultParallelConfig(),
+			start: func(run *pipelineRun) (int, pipelineEmit[indexed[T]]) {
+				out := &run.stages[0].out
+				return len(data), func(lo, hi int, yield func(indexed[T]) bool) {
+					count := 0
+					defer func() { out.Add(int64(count)) }()
+					for i := lo; i < hi && !run.stopped.Load(); i++ {
+						count++
+						if !yield(indexed[T]{index: i, value: data[i]}) {
+							return
+						}
+					}
+				}
+			},
+			plan: []pipelineStage{{name: "Source"}},
+		},
+	}
+}
+
+// WithConfig sets the configuration
+func (tp *TryPipeline[T]) WithConfig(config ParallelConfig) *TryPipeline[T] {
+	tp.pipeline.config = config
+	return tp
+}
+
+// WithPolicy sets the error policy.
+// With SkipErrors every failed item is skipped and its error is collected with its index.
+func (tp *TryPipeline[T]) WithPolicy(policy ErrorPolicy) *TryPipeline[T] {
+	tp.policy = policy
+	return tp
+}
+
+// WithDeadLetter routes failed items to the sink and switches to the DeadLetter policy.
+// The sink is never called concurrently.
+func (tp *TryPipeline[T]) WithDeadLetter(sink func(error)) *TryPipeline[T] {
+	tp.policy = DeadLetter
+	tp.sink = sink
+	return tp
+}
+
+// Map applies a context-aware transformation function that may fail
+func (tp *TryPipeline[T]) Map(mapper func(context.Context, T) (T, error)) *TryPipeline[T] {
+	tp.pipeline = TryPipelineMap(tp, mapper).pipeline
+	return tp
+}
+
+// Filter applies a filtering function
+func (tp *TryPipeline[T]) Filter(predicate Predicate[T]) *TryPipeline[T] {
+	tp.pipeline.Filter(func(item indexed[T]) bool {
+		return predicate(item.value)
+	})
+	return tp
+}
+
+// TryPipelineMap applies a context-aware transformation function that may fail
+// and may change the element type
+func TryPipelineMap[T, R any](tp *TryPipeline[T], mapper func(context.Context, T) (R, error)) *TryPipeline[R] {
+	return &TryPipeline[R]{
+		ctx: tp.ctx,
+		pipeline: fuse(tp.pipeline, "Map", func(run *pipelineRun, emit pipelineEmit[indexed[T]], out *atomic.Int64) pipelineEmit[indexed[R]] {
+			return func(lo, hi int, yield func(indexed[R]) bool) {
+				count := 0
+				defer func() { out.Add(int64(count)) }()
+				emit(lo, hi, func(item indexed[T]) bool {
+					value, err := mapper(run.ctx, item.value)
+					if err != nil {
+						return run.fail(item.index, err)
+					}
+					count++
+					return yield(indexed[R]{index: item.index, value: value})
+				})
+			}
+		}),
+		policy: tp.policy,
+		sink:   tp.sink,
+	}
+}
+
+// Collect runs the pipeline and returns the successful items in input order.
+// With FailFast the first error is returned, with SkipErrors all errors are
+// joined in input order, with DeadLetter only the error of a cancelled context is returned.
+func (tp *TryPipeline[T]) Collect() ([]T, error) {
+	run := newPipelineRun(tp.ctx, tp.policy, tp.sink, len(tp.pipeline.plan))
+	defer run.close()
+	defer tp.pipeline.last.Store(run)
+
+	items := tp.pipeline.collect(run)
+	if err := run.err(tp.ctx); err != nil && tp.policy == FailFast {
+		return nil, err
+	}
+	return Map(items, func(item indexed[T]) T {
+		return item.value
+	}), run.err(tp.ctx)
+}
+
+// Reduce runs the pipeline and reduces the successful items.
+// Chunks are reduced in parallel, so the reducer must be associative.
+func (tp *TryPipeline[T]) Reduce(reducer func(T, T) T, identity T) (T, error) {
+	run := newPipelineRun(tp.ctx, tp.policy, tp.sink, len(tp.pipeline.plan))
+	defer run.close()
+	defer tp.pipeline.last.Store(run)
+
+	result := tp.pipeline.reduce(run, func(a, b indexed[T]) indexed[T] {
+		return indexed[T]{value: reducer(a.value, b.value)}
+	}, indexed[T]{value: identity})
+	if err := run.err(tp.ctx); err != nil {
+		if tp.policy == FailFast {
+			return identity, err
+		}
+		return result.value, err
+	}
+	return result.value, nil
+}
+
+// Explain describes the recorded stages, one per line, with the way they run
+func (tp *TryPipeline[T]) Explain() string {
+	return tp.pipeline.Explain()
+}
+
+// Stats reports every stage of the last run of the pipeline, nil before the first run
+func (tp *TryPipeline[T]) Stats() []PipelineStageStats {
+	return tp.pipeline.Stats()
+}
@@ -0,0 +1,184 @@
+package fp
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns the stream as an iterator for range-over-func.
+// Breaking out of the loop stops the stream.
+func (s *Stream[T]) All() iter.Seq[T] {
+	return s.run()
+}
+
+// FromSeq creates a new stream from an iterator
+func FromSeq[T any](seq iter.Seq[T]) *Stream[T] {
+	return newStream(func(_ context.Context, yield func(T) bool) {
+		seq(yield)
+	})
+}
+
+// MapSeq lazily applies a transformation function to each element of the iterator
+func MapSeq[T, R any](seq iter.Seq[T], mapper Mapper[T, R]) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for item := range seq {
+			if !yield(mapper(item)) {
+				return
+			}
+		}
+	}
+}
+
+// FilterSeq lazily filters elements of the iterator by predicate
+func FilterSeq[T any](seq iter.Seq[T], predicate Predicate[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range seq {
+			if predicate(item) && !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhileSeq lazily yields elements while the predicate is true
+func TakeWhileSeq[T any](seq iter.Seq[T], predicate Predicate[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range seq {
+			if !predicate(item) || !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// DropWhileSeq lazily skips elements while the predicate is true and yields the rest
+func DropWhileSeq[T any](seq iter.Seq[T], predicate Predicate[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		dropping := true
+		for item := range seq {
+			if dropping && predicate(item) {
+				continue
+			}
+			dropping = false
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// IntersperseSeq lazily yields the separator between the elements of the iterator
+func IntersperseSeq[T any](seq iter.Seq[T], separator T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		first := true
+		for item := range seq {
+			if !first && !yield(separator) {
+				return
+			}
+			first = false
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ChunkSeq lazily splits the iterator into chunks of a given size
+func ChunkSeq[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		if size <= 0 {
+			return
+		}
+
+		chunk := make([]T, 0, size)
+		for item := range seq {
+			chunk = append(chunk, item)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// ZipSeq lazily zips two iterators into pairs, stopping at the shorter one
+func ZipSeq[T, R any](seq1 iter.Seq[T], seq2 iter.Seq[R]) iter.Seq[Pair[T, R]] {
+	return func(yield func(Pair[T, R]) bool) {
+		next, stop := iter.Pull(seq2)
+		defer stop()
+
+		for first := range seq1 {
+			second, ok := next()
+			if !ok {
+				return
+			}
+			if !yield(Pair[T, R]{First: first, Second: second}) {
+				return
+			}
+		}
+	}
+}
+
+// SlidingSeq lazily yields sliding windows of a given size
+func SlidingSeq[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		if size <= 0 {
+			return
+		}
+
+		buffer := make([]T, 0, size)
+		for item := range seq {
+			if len(buffer) == size {
+				copy(buffer, buffer[1:])
+				buffer = buffer[:size-1]
+			}
+			buffer = append(buffer, item)
+			if len(buffer) == size {
+				window := make([]T, size)
+				copy(window, buffer)
+				if !yield(window) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KeysSeq returns an iterator over the keys of a map
+func KeysSeq[K comparable, V any](m map[K]V) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// ValuesSeq returns an iterator over the values of a map
+func ValuesSeq[K comparable, V any](m map[K]V) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// EntriesSeq returns an iterator over the key-value pairs of a map
+func EntriesSeq[K comparable, V any](m map[K]V) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range m {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
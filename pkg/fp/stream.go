@@ -2,141 +2,331 @@ package fp
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 // Stream represents a stream of data for lazy evaluations
+//
+// Sequential stages are fused: the terminal operation drives a single loop
+// that pulls every element through all stages without intermediate channels.
+// Goroutines are started only by the stages that need them: Parallel,
+// ParallelOrdered, the retry stages, Buffer and BufferWithPolicy, the time
+// windows, the time operators (Debounce, Sample, Delay), MergeStreams, Tee
+// and Broadcast. Retry attempts with a timeout also run in their own goroutine.
+// When a terminal or a limit such as Take stops early, the loop simply
+// returns, so nothing upstream is left running.
+//
+// The context bound with WithContext is handed to the source and every stage
+// when a terminal runs the stream, so cancelling it stops the whole chain.
+// An observer bound with WithObserver travels the same way.
 type Stream[T any] struct {
-	source   func() <-chan T
-	pipeline []func(<-chan T) <-chan T
+	source   func(ctx context.Context) iter.Seq[T]
+	ctx      context.Context
+	observer StreamObserver
+	// stopped is the error that stopped the last run early
+	stopped atomic.Pointer[error]
 }
 
-// NewStream creates a new stream from a slice
-func NewStream[T any](slice []T) *Stream[T] {
+// newStream creates a stream from a source. The source stops as soon as the
+// context is cancelled, even if it never looks at the context itself.
+func newStream[T any](source func(ctx context.Context, yield func(T) bool)) *Stream[T] {
 	return &Stream[T]{
-		source: func() <-chan T {
-			ch := make(chan T, len(slice))
-			go func() {
-				defer close(ch)
-				for _, item := range slice {
-					ch <- item
+		source: func(ctx context.Context) iter.Seq[T] {
+			return func(yield func(T) bool) {
+				if err := ctx.Err(); err != nil {
+					stopRun(ctx, err)
+					return
 				}
-			}()
-			return ch
+				_, ctx := stageNameFrom(ctx, "")
+				source(ctx, func(item T) bool {
+					if err := ctx.Err(); err != nil {
+						stopRun(ctx, err)
+						return false
+					}
+					return yield(item)
+				})
+				// Sources that watch the context themselves return without yielding
+				if err := ctx.Err(); err != nil {
+					stopRun(ctx, err)
+				}
+			}
 		},
-		pipeline: []func(<-chan T) <-chan T{},
 	}
 }
 
-// NewStreamFromChannel creates a new stream from a channel
-func NewStreamFromChannel[T any](ch <-chan T) *Stream[T] {
-	return &Stream[T]{
-		source:   func() <-chan T { return ch },
-		pipeline: []func(<-chan T) <-chan T{},
+// pipe attaches a stage to the stream. The stage may change the element type,
+// so every stream is simply its upstream source composed with the stage.
+// The name identifies the stage in observer reports.
+func pipe[T, R any](s *Stream[T], name string, stage func(iter.Seq[T]) iter.Seq[R]) *Stream[R] {
+	return pipeCtx(s, name, func(_ context.Context, input iter.Seq[T]) iter.Seq[R] {
+		return stage(input)
+	})
+}
+
+// pipeCtx attaches a stage that needs the context of the running stream.
+// The stage is reported under the given name unless Named renames it, and it
+// is observed only if an observer travels with the context.
+func pipeCtx[T, R any](s *Stream[T], name string, stage func(context.Context, iter.Seq[T]) iter.Seq[R]) *Stream[R] {
+	return &Stream[R]{
+		source: func(ctx context.Context) iter.Seq[R] {
+			name, ctx := stageNameFrom(ctx, name)
+			observer, _ := ctx.Value(observerKey{}).(StreamObserver)
+			if observer == nil {
+				return stage(ctx, s.source(ctx))
+			}
+			probe := &stageProbe{observer: observer, name: name}
+			return observeOutput(probe, stage(ctx, observeInput(probe, s.source(ctx))))
+		},
+		ctx:      s.ctx,
+		observer: s.observer,
 	}
 }
 
-// NewStreamFromFunc creates a new stream from a generator function
-func NewStreamFromFunc[T any](generator func() <-chan T) *Stream[T] {
-	return &Stream[T]{
-		source:   generator,
-		pipeline: []func(<-chan T) <-chan T{},
+// context returns the context bound to the stream
+func (s *Stream[T]) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
 	}
+	return s.ctx
 }
 
-// Map applies a transformation function to the stream
-func (s *Stream[T]) Map(mapper Mapper[T, T]) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
-			for item := range input {
-				output <- mapper(item)
+// stoppedKey is the context key of the place where a run records the error that stopped it
+type stoppedKey struct{}
+
+// run starts the stream under its bound context
+func (s *Stream[T]) run() iter.Seq[T] {
+	ctx := context.WithValue(s.context(), stoppedKey{}, &s.stopped)
+	if s.observer != nil {
+		ctx = context.WithValue(ctx, observerKey{}, s.observer)
+	}
+	source := s.source(ctx)
+	return func(yield func(T) bool) {
+		s.stopped.Store(nil)
+		source(yield)
+	}
+}
+
+// stopRun records the error that stopped the run early
+func stopRun(ctx context.Context, err error) {
+	if stopped, ok := ctx.Value(stoppedKey{}).(*atomic.Pointer[error]); ok {
+		stopped.CompareAndSwap(nil, &err)
+	}
+}
+
+// open runs the stream as the input of a stream that runs under ctx.
+// The stream keeps the context and the observer bound to it, and it also
+// stops when ctx is cancelled.
+func (s *Stream[T]) open(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		run := ctx
+		if s.ctx != nil {
+			linked, cancel := context.WithCancel(s.ctx)
+			defer cancel()
+			defer context.AfterFunc(ctx, cancel)()
+			run = context.WithValue(linked, stoppedKey{}, ctx.Value(stoppedKey{}))
+			if observer, ok := ctx.Value(observerKey{}).(StreamObserver); ok {
+				run = context.WithValue(run, observerKey{}, observer)
 			}
-		}()
-		return output
-	})
+		}
+		if s.observer != nil {
+			run = context.WithValue(run, observerKey{}, s.observer)
+		}
 
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
+		for item := range s.source(run) {
+			if !yield(item) {
+				return
+			}
+		}
 	}
 }
 
-// Filter applies a filtering function to the stream
-func (s *Stream[T]) Filter(predicate Predicate[T]) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
-			for item := range input {
-				if predicate(item) {
-					output <- item
+// send delivers an item unless the stream has been cancelled
+func send[T any](done <-chan struct{}, output chan<- T, item T) bool {
+	select {
+	case output <- item:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// drain yields everything received from the channel and closes done
+// as soon as the consumer stops, which releases the producing goroutines
+func drain[T any](ch <-chan T, done chan struct{}, yield func(T) bool) {
+	defer close(done)
+
+	for item := range ch {
+		if !yield(item) {
+			return
+		}
+	}
+}
+
+// pump runs the upstream stages in their own goroutine and delivers the items
+// through a channel with the given capacity until done is closed
+func pump[T any](input iter.Seq[T], size int, done <-chan struct{}) <-chan T {
+	output := make(chan T, size)
+	go func() {
+		defer close(output)
+		for item := range input {
+			if !send(done, output, item) {
+				return
+			}
+		}
+	}()
+	return output
+}
+
+// singleUse guards a source that cannot be replayed, such as a channel or a reader.
+// Consuming it a second time panics instead of silently producing nothing.
+func singleUse[T any](kind string, source func(context.Context, func(T) bool)) func(context.Context, func(T) bool) {
+	var consumed atomic.Bool
+	return func(ctx context.Context, yield func(T) bool) {
+		if consumed.Swap(true) {
+			panic(fmt.Sprintf("fp: stream from %s has already been consumed, use Replay to consume it more than once", kind))
+		}
+		source(ctx, yield)
+	}
+}
+
+// NewStream creates a new stream from a slice
+func NewStream[T any](slice []T) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for _, item := range slice {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// NewStreamFromChannel creates a new stream from a channel.
+// The channel is owned by the caller, so stopping the stream stops reading
+// from it but does not stop whoever writes to it.
+// The stream can be consumed only once, use Replay to consume it repeatedly.
+func NewStreamFromChannel[T any](ch <-chan T) *Stream[T] {
+	return newStream(singleUse("channel", func(ctx context.Context, yield func(T) bool) {
+		for {
+			select {
+			case item, ok := <-ch:
+				if !ok || !yield(item) {
+					return
 				}
+			case <-ctx.Done():
+				return
 			}
-		}()
-		return output
+		}
+	}))
+}
+
+// NewStreamFromFunc creates a new stream from a generator function
+func NewStreamFromFunc[T any](generator func() <-chan T) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for item := range generator() {
+			if !yield(item) {
+				return
+			}
+		}
 	})
+}
 
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
-	}
+// Map applies a transformation function to the stream
+func (s *Stream[T]) Map(mapper Mapper[T, T]) *Stream[T] {
+	return pipe(s, "Map", func(input iter.Seq[T]) iter.Seq[T] {
+		return MapSeq(input, mapper)
+	})
+}
+
+// Filter applies a filtering function to the stream
+func (s *Stream[T]) Filter(predicate Predicate[T]) *Stream[T] {
+	return pipe(s, "Filter", func(input iter.Seq[T]) iter.Seq[T] {
+		return FilterSeq(input, predicate)
+	})
 }
 
 // Take takes the first n elements from the stream
 func (s *Stream[T]) Take(n int) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
+	return pipe(s, "Take", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			if n <= 0 {
+				return
+			}
 			count := 0
 			for item := range input {
-				if count >= n {
-					break
+				if !yield(item) {
+					return
 				}
-				output <- item
 				count++
+				if count >= n {
+					return
+				}
 			}
-		}()
-		return output
+		}
 	})
-
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
-	}
 }
 
 // Skip skips the first n elements from the stream
 func (s *Stream[T]) Skip(n int) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
+	return pipe(s, "Skip", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
 			count := 0
 			for item := range input {
 				if count >= n {
-					output <- item
+					if !yield(item) {
+						return
+					}
 				}
 				count++
 			}
-		}()
-		return output
+		}
 	})
+}
 
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
-	}
+// TakeWhile takes elements while the predicate is true
+func (s *Stream[T]) TakeWhile(predicate Predicate[T]) *Stream[T] {
+	return pipe(s, "TakeWhile", func(input iter.Seq[T]) iter.Seq[T] {
+		return TakeWhileSeq(input, predicate)
+	})
+}
+
+// DropWhile drops elements while the predicate is true
+func (s *Stream[T]) DropWhile(predicate Predicate[T]) *Stream[T] {
+	return pipe(s, "DropWhile", func(input iter.Seq[T]) iter.Seq[T] {
+		return DropWhileSeq(input, predicate)
+	})
+}
+
+// Peek calls the action for every element as it passes through the stream
+func (s *Stream[T]) Peek(action func(T)) *Stream[T] {
+	return pipe(s, "Peek", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			for item := range input {
+				action(item)
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Intersperse inserts the separator between the elements of the stream
+func (s *Stream[T]) Intersperse(separator T) *Stream[T] {
+	return pipe(s, "Intersperse", func(input iter.Seq[T]) iter.Seq[T] {
+		return IntersperseSeq(input, separator)
+	})
 }
 
-// Distinct removes duplicates from the stream
+// Distinct removes duplicates from the stream.
+// Every item is compared with all previously emitted ones, prefer DistinctBy for large streams.
 func (s *Stream[T]) Distinct(equals Equality[T]) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
+	return pipe(s, "Distinct", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
 			var seen []T
 			for item := range input {
 				isDuplicate := false
@@ -148,51 +338,38 @@ func (s *Stream[T]) Distinct(equals Equality[T]) *Stream[T] {
 				}
 				if !isDuplicate {
 					seen = append(seen, item)
-					output <- item
+					if !yield(item) {
+						return
+					}
 				}
 			}
-		}()
-		return output
+		}
 	})
-
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
-	}
 }
 
 // DistinctComparable removes duplicates for comparable types
 func (s *Stream[T]) DistinctComparable() *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
+	return pipe(s, "DistinctComparable", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
 			seen := make(map[interface{}]bool)
 			for item := range input {
 				if !seen[item] {
 					seen[item] = true
-					output <- item
+					if !yield(item) {
+						return
+					}
 				}
 			}
-		}()
-		return output
+		}
 	})
-
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
-	}
 }
 
 // Parallel applies parallel processing to the stream
 func (s *Stream[T]) Parallel(workerCount int, processor func(T) T) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-
-		go func() {
-			defer close(output)
-
+	return pipe(s, "Parallel", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
 			var wg sync.WaitGroup
+			done := make(chan struct{})
 			jobs := make(chan T, workerCount*2)
 			results := make(chan T, workerCount*2)
 
@@ -202,7 +379,9 @@ func (s *Stream[T]) Parallel(workerCount int, processor func(T) T) *Stream[T] {
 				go func() {
 					defer wg.Done()
 					for job := range jobs {
-						results <- processor(job)
+						if !send(done, results, processor(job)) {
+							return
+						}
 					}
 				}()
 			}
@@ -211,7 +390,9 @@ func (s *Stream[T]) Parallel(workerCount int, processor func(T) T) *Stream[T] {
 			go func() {
 				defer close(jobs)
 				for item := range input {
-					jobs <- item
+					if !send(done, jobs, item) {
+						return
+					}
 				}
 			}()
 
@@ -221,124 +402,377 @@ func (s *Stream[T]) Parallel(workerCount int, processor func(T) T) *Stream[T] {
 				close(results)
 			}()
 
-			for result := range results {
-				output <- result
-			}
-		}()
+			drain(results, done, yield)
+		}
+	})
+}
 
-		return output
+// ParallelOrdered applies parallel processing to the stream and keeps the input order.
+// At most window items are in flight or waiting to be re-sequenced, so a slow item
+// at the head of the stream pauses the intake instead of growing the reorder buffer.
+func (s *Stream[T]) ParallelOrdered(workerCount, window int, processor func(T) T) *Stream[T] {
+	return pipe(s, "ParallelOrdered", func(input iter.Seq[T]) iter.Seq[T] {
+		return parallelOrdered(input, workerCount, window, processor)
 	})
+}
 
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
+// parallelOrdered runs the processor on workerCount goroutines and re-sequences
+// the results through a reorder window
+func parallelOrdered[T, R any](input iter.Seq[T], workerCount, window int, processor func(T) R) iter.Seq[R] {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	if window < workerCount {
+		window = workerCount
 	}
-}
 
-// Buffer 	buffers the stream
-func (s *Stream[T]) Buffer(size int) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T, size)
+	type job struct {
+		index int
+		item  T
+	}
+	type result struct {
+		index int
+		item  R
+	}
+
+	return func(yield func(R) bool) {
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+		slots := make(chan struct{}, window)
+		jobs := make(chan job, workerCount)
+		results := make(chan result, window)
+
+		for i := 0; i < workerCount; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					if !send(done, results, result{index: j.index, item: processor(j.item)}) {
+						return
+					}
+				}
+			}()
+		}
+
 		go func() {
-			defer close(output)
+			defer close(jobs)
+			index := 0
 			for item := range input {
-				output <- item
+				if !send(done, slots, struct{}{}) || !send(done, jobs, job{index: index, item: item}) {
+					return
+				}
+				index++
 			}
 		}()
-		return output
+
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		defer close(done)
+
+		pending := make(map[int]R, window)
+		next := 0
+		for res := range results {
+			pending[res.index] = res.item
+			for {
+				item, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				<-slots
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Buffer buffers the stream, running the upstream stages in their own goroutine
+func (s *Stream[T]) Buffer(size int) *Stream[T] {
+	return pipe(s, "Buffer", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			done := make(chan struct{})
+			drain(pump(input, size, done), done, yield)
+		}
 	})
+}
 
+// WithContext binds a context to the whole stream, replacing any context bound earlier.
+// Sources and stages both before and after this call stop once it is cancelled.
+func (s *Stream[T]) WithContext(ctx context.Context) *Stream[T] {
 	return &Stream[T]{
 		source:   s.source,
-		pipeline: newPipeline,
+		ctx:      ctx,
+		observer: s.observer,
 	}
 }
 
-// WithContext adds a context to the stream
-func (s *Stream[T]) WithContext(ctx context.Context) *Stream[T] {
-	newPipeline := append(s.pipeline, func(input <-chan T) <-chan T {
-		output := make(chan T)
-		go func() {
-			defer close(output)
-			for {
-				select {
-				case item, ok := <-input:
-					if !ok {
-						return
-					}
-					select {
-					case output <- item:
-					case <-ctx.Done():
-						return
-					}
-				case <-ctx.Done():
-					return
+// Err returns the error that stopped the last run of the stream early, such as
+// the error of a cancelled context, so after a terminal returns the caller can
+// tell a cut short run from a complete one.
+// It is nil for a run that ended normally, even if the context is cancelled later.
+func (s *Stream[T]) Err() error {
+	if err := s.stopped.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// ErrReplayCut is reported by consumers of a replayed stream whose upstream
+// was stopped before its end, because its only consumer was cancelled
+var ErrReplayCut = errors.New("fp: replayed stream was cut before its end")
+
+// Replay memoizes the stream so it can be consumed any number of times.
+// The upstream runs at most once: items are recorded as they are first pulled,
+// and later consumers replay them before continuing from where the source stopped.
+// When a consumer stops early the upstream is paused, not stopped, so a later
+// consumer still gets every item. The upstream is stopped before its end only
+// when a consumer is cancelled while no other one is running, or once the
+// replayed stream is no longer reachable; consumers that then run out of
+// recorded items report the cause with Err.
+//
+// The upstream runs under its own bound context and the observer of the run that opens it.
+func (s *Stream[T]) Replay() *Stream[T] {
+	released, release := context.WithCancel(context.Background())
+	r := &replay[T]{wake: make(chan struct{}), released: released, release: release}
+	handle := &replayHandle[T]{r}
+	runtime.AddCleanup(handle, func(r *replay[T]) { r.cut(ErrReplayCut) }, r)
+
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		defer runtime.KeepAlive(handle)
+
+		r.live.Add(1)
+		var once sync.Once
+		leave := func(cancelled bool) {
+			once.Do(func() {
+				if r.live.Add(-1) == 0 && cancelled {
+					r.cut(fmt.Errorf("%w: %w", ErrReplayCut, ctx.Err()))
 				}
+			})
+		}
+		defer func() { leave(ctx.Err() != nil) }()
+		defer context.AfterFunc(ctx, func() { leave(true) })()
+
+		for i := 0; ; i++ {
+			item, ok := r.at(s, ctx, i)
+			if !ok {
+				if err := r.stopped.Load(); err != nil && ctx.Err() == nil {
+					stopRun(ctx, *err)
+				}
+				return
 			}
-		}()
-		return output
+			if !yield(item) {
+				return
+			}
+		}
 	})
+}
 
-	return &Stream[T]{
-		source:   s.source,
-		pipeline: newPipeline,
+// replay is the state shared by the consumers of a replayed stream
+type replay[T any] struct {
+	mu    sync.Mutex
+	items []T
+	next  func() (T, bool)
+	stop  func()
+	ended bool
+	// pulling is set while a consumer waits for the upstream outside the lock,
+	// and wake is closed and replaced whenever that wait is over
+	pulling bool
+	wake    chan struct{}
+
+	// stopped is the error that stopped the upstream before its end
+	stopped  atomic.Pointer[error]
+	live     atomic.Int64
+	opened   atomic.Bool
+	released context.Context
+	release  context.CancelFunc
+}
+
+// replayHandle is referenced by every copy of a replayed stream and by nothing
+// the upstream holds, so it becomes unreachable once no consumer can come
+type replayHandle[T any] struct {
+	*replay[T]
+}
+
+// at returns the i-th item, pulling the upstream of s as far as needed.
+// One consumer pulls at a time while the others wait for it or for their
+// context, so a cancelled consumer never waits for the upstream of another.
+func (r *replay[T]) at(s *Stream[T], ctx context.Context, i int) (T, bool) {
+	var zero T
+	r.mu.Lock()
+	for {
+		if i < len(r.items) {
+			item := r.items[i]
+			r.mu.Unlock()
+			return item, true
+		}
+		if r.ended {
+			r.mu.Unlock()
+			return zero, false
+		}
+		if r.pulling {
+			wake := r.wake
+			r.mu.Unlock()
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return zero, false
+			}
+			r.mu.Lock()
+			continue
+		}
+
+		if r.next == nil {
+			// The upstream is shared, so it does not take the context of the
+			// consumer that opens it and is cancelled only by cut
+			upstream := context.WithValue(context.Background(), stoppedKey{}, &r.stopped)
+			if observer, ok := ctx.Value(observerKey{}).(StreamObserver); ok {
+				upstream = context.WithValue(upstream, observerKey{}, observer)
+			}
+			upstream, cancel := context.WithCancel(upstream)
+			context.AfterFunc(r.released, cancel)
+			r.opened.Store(true)
+			r.next, r.stop = iter.Pull(s.open(upstream))
+		}
+		// A consumer cancelled while the upstream was not yet open does not cut it
+		if ctx.Err() != nil {
+			r.mu.Unlock()
+			return zero, false
+		}
+		r.pulling = true
+		r.mu.Unlock()
+
+		item, ok := r.next()
+
+		r.mu.Lock()
+		r.pulling = false
+		switch {
+		case r.ended:
+			// cut while the upstream was being pulled
+			r.stop()
+		case !ok:
+			r.ended = true
+			r.stop()
+			r.release()
+		default:
+			r.items = append(r.items, item)
+		}
+		close(r.wake)
+		r.wake = make(chan struct{})
 	}
 }
 
-// Collect collects all elements from the stream into a slice
-func (s *Stream[T]) Collect() []T {
-	ch := s.source()
+// cut stops the upstream before its end and records why
+func (r *replay[T]) cut(err error) {
+	if !r.opened.Load() {
+		return
+	}
+	r.stopped.CompareAndSwap(nil, &err)
+	r.release()
 
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ended {
+		return
 	}
+	r.ended = true
+	// A consumer pulling the upstream stops it once the pull returns
+	if !r.pulling {
+		r.stop()
+	}
+	close(r.wake)
+	r.wake = make(chan struct{})
+}
 
+// Collect collects all elements from the stream into a slice
+func (s *Stream[T]) Collect() []T {
 	var result []T
-	for item := range ch {
+	for item := range s.run() {
 		result = append(result, item)
 	}
 
 	return result
 }
 
-// CollectToChannel collects the stream into a channel
+// CollectToChannel collects the stream into a channel.
+// The stream runs until the channel is drained, so the caller must read it to the end,
+// use Iterator to stop early.
 func (s *Stream[T]) CollectToChannel() <-chan T {
-	ch := s.source()
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for item := range s.run() {
+			ch <- item
+		}
+	}()
+	return ch
+}
 
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
+// Iterator returns a pull-style iterator over the stream.
+// The stream starts on the first call to Next. Call Close when done, even if the
+// stream was not read to the end, to release upstream goroutines.
+func (s *Stream[T]) Iterator() *StreamIterator[T] {
+	return &StreamIterator[T]{stream: s}
+}
+
+// StreamIterator consumes a stream one item at a time.
+// It is not safe for concurrent use.
+type StreamIterator[T any] struct {
+	stream *Stream[T]
+	next   func() (T, bool)
+	stop   func()
+	closed bool
+}
+
+// Next returns the next item, or false once the stream is exhausted, cancelled or closed
+func (it *StreamIterator[T]) Next() (T, bool) {
+	if it.closed {
+		var zero T
+		return zero, false
+	}
+	if it.next == nil {
+		it.next, it.stop = iter.Pull(it.stream.run())
 	}
 
-	return ch
+	item, ok := it.next()
+	if !ok {
+		it.Close()
+	}
+	return item, ok
 }
 
-// ForEach executes a function for each element in the stream
-func (s *Stream[T]) ForEach(action func(T)) {
-	ch := s.source()
+// Err returns the error of the context bound to the stream if it ended the iteration
+func (it *StreamIterator[T]) Err() error {
+	return it.stream.Err()
+}
 
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
+// Close stops the stream and releases its goroutines. It is safe to call more than once.
+func (it *StreamIterator[T]) Close() {
+	if it.closed {
+		return
+	}
+	it.closed = true
+	if it.stop != nil {
+		it.stop()
 	}
+}
 
-	for item := range ch {
+// ForEach executes a function for each element in the stream
+func (s *Stream[T]) ForEach(action func(T)) {
+	for item := range s.run() {
 		action(item)
 	}
 }
 
 // Reduce reduces the stream to a single value
 func (s *Stream[T]) Reduce(reducer Reducer[T, T], initial T) T {
-	ch := s.source()
-
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
-	}
-
 	result := initial
-	for item := range ch {
+	for item := range s.run() {
 		result = reducer(result, item)
 	}
 
@@ -347,15 +781,8 @@ func (s *Stream[T]) Reduce(reducer Reducer[T, T], initial T) T {
 
 // Count counts the number of elements in the stream
 func (s *Stream[T]) Count() int {
-	ch := s.source()
-
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
-	}
-
 	count := 0
-	for range ch {
+	for range s.run() {
 		count++
 	}
 
@@ -364,14 +791,7 @@ func (s *Stream[T]) Count() int {
 
 // AnyMatch checks if any element matches the predicate
 func (s *Stream[T]) AnyMatch(predicate Predicate[T]) bool {
-	ch := s.source()
-
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
-	}
-
-	for item := range ch {
+	for item := range s.run() {
 		if predicate(item) {
 			return true
 		}
@@ -382,14 +802,7 @@ func (s *Stream[T]) AnyMatch(predicate Predicate[T]) bool {
 
 // AllMatch checks if all elements match the predicate
 func (s *Stream[T]) AllMatch(predicate Predicate[T]) bool {
-	ch := s.source()
-
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
-	}
-
-	for item := range ch {
+	for item := range s.run() {
 		if !predicate(item) {
 			return false
 		}
@@ -400,14 +813,7 @@ func (s *Stream[T]) AllMatch(predicate Predicate[T]) bool {
 
 // FindFirst finds the first element matching the predicate
 func (s *Stream[T]) FindFirst(predicate Predicate[T]) Optional[T] {
-	ch := s.source()
-
-	// Apply all pipeline stages
-	for _, stage := range s.pipeline {
-		ch = stage(ch)
-	}
-
-	for item := range ch {
+	for item := range s.run() {
 		if predicate(item) {
 			return Some(item)
 		}
@@ -416,6 +822,65 @@ func (s *Stream[T]) FindFirst(predicate Predicate[T]) Optional[T] {
 	return Empty[T]()
 }
 
+// Type-changing stages
+
+// StreamMap applies a transformation function that may change the element type
+func StreamMap[T, R any](s *Stream[T], mapper Mapper[T, R]) *Stream[R] {
+	return pipe(s, "StreamMap", func(input iter.Seq[T]) iter.Seq[R] {
+		return MapSeq(input, mapper)
+	})
+}
+
+// StreamFlatMap applies a function and flattens the results into the stream
+func StreamFlatMap[T, R any](s *Stream[T], mapper func(T) []R) *Stream[R] {
+	return pipe(s, "StreamFlatMap", func(input iter.Seq[T]) iter.Seq[R] {
+		return func(yield func(R) bool) {
+			for item := range input {
+				for _, mapped := range mapper(item) {
+					if !yield(mapped) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// StreamZip zips two streams into a stream of pairs, stopping at the shorter one
+func StreamZip[T, R any](s1 *Stream[T], s2 *Stream[R]) *Stream[Pair[T, R]] {
+	return pipeCtx(s1, "StreamZip", func(ctx context.Context, input iter.Seq[T]) iter.Seq[Pair[T, R]] {
+		return ZipSeq(input, s2.open(ctx))
+	})
+}
+
+// StreamScan emits every intermediate result of reducing the stream
+func StreamScan[T, R any](s *Stream[T], reducer Reducer[T, R], initial R) *Stream[R] {
+	return pipe(s, "StreamScan", func(input iter.Seq[T]) iter.Seq[R] {
+		return func(yield func(R) bool) {
+			acc := initial
+			for item := range input {
+				acc = reducer(acc, item)
+				if !yield(acc) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// StreamChunk is an alias of TumblingWindow named after the slice function Chunk
+func StreamChunk[T any](s *Stream[T], size int) *Stream[[]T] {
+	return TumblingWindow(s, size)
+}
+
+// StreamSliding emits sliding windows of a given size.
+// A stream shorter than size produces no windows.
+func StreamSliding[T any](s *Stream[T], size int) *Stream[[]T] {
+	return pipe(s, "StreamSliding", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return SlidingSeq(input, size)
+	})
+}
+
 // StreamBuilder helps to create streams
 type StreamBuilder[T any] struct {
 	items []T
@@ -445,50 +910,121 @@ func (sb *StreamBuilder[T]) Build() *Stream[T] {
 
 // InfiniteStream creates an infinite stream
 func InfiniteStream[T any](generator func() T) *Stream[T] {
-	return &Stream[T]{
-		source: func() <-chan T {
-			ch := make(chan T)
-			go func() {
-				for {
-					ch <- generator()
-				}
-			}()
-			return ch
-		},
-		pipeline: []func(<-chan T) <-chan T{},
-	}
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for {
+			if !yield(generator()) {
+				return
+			}
+		}
+	})
 }
 
 // RangeStream creates a stream of numbers from start to end
 func RangeStream(start, end int) *Stream[int] {
-	return &Stream[int]{
-		source: func() <-chan int {
-			ch := make(chan int)
-			go func() {
-				defer close(ch)
-				for i := start; i < end; i++ {
-					ch <- i
-				}
-			}()
-			return ch
-		},
-		pipeline: []func(<-chan int) <-chan int{},
-	}
+	return newStream(func(ctx context.Context, yield func(int) bool) {
+		for i := start; i < end; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
 }
 
 // RepeatStream creates a stream repeating the value n times
 func RepeatStream[T any](value T, count int) *Stream[T] {
-	return &Stream[T]{
-		source: func() <-chan T {
-			ch := make(chan T)
-			go func() {
-				defer close(ch)
-				for i := 0; i < count; i++ {
-					ch <- value
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for i := 0; i < count; i++ {
+			if !yield(value) {
+				return
+			}
+		}
+	})
+}
+
+// RangeStepStream creates a stream of numbers from start towards end, exclusive, by step.
+// A negative step counts down, a zero step produces an empty stream. Values are
+// computed as start + i*step, so floating point steps do not accumulate error.
+// The stream ends early once the values stop moving towards end, which happens
+// when an integer type would overflow or a float type runs out of precision.
+func RangeStepStream[T ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64](start, end, step T) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		if step == 0 {
+			return
+		}
+		previous := start
+		for i := int64(0); ; i++ {
+			value := start + T(i)*step
+			if step > 0 && (value >= end || (i > 0 && value <= previous)) {
+				return
+			}
+			if step < 0 && (value <= end || (i > 0 && value >= previous)) {
+				return
+			}
+			if !yield(value) {
+				return
+			}
+			previous = value
+		}
+	})
+}
+
+// Unfold creates a stream from a seed. The step function returns the next item
+// and the next state, or false to end the stream.
+//
+//	fibonacci := Unfold([2]int{0, 1}, func(s [2]int) (int, [2]int, bool) {
+//		return s[0], [2]int{s[1], s[0] + s[1]}, true
+//	})
+func Unfold[S, T any](seed S, step func(S) (T, S, bool)) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		state := seed
+		for {
+			item, next, ok := step(state)
+			if !ok || !yield(item) {
+				return
+			}
+			state = next
+		}
+	})
+}
+
+// Iterate creates an infinite stream of seed, f(seed), f(f(seed)) and so on
+func Iterate[T any](seed T, f func(T) T) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for item := seed; ; item = f(item) {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// Cycle creates an infinite stream repeating the elements of the slice.
+// An empty slice produces an empty stream.
+func Cycle[T any](slice []T) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		if len(slice) == 0 {
+			return
+		}
+		for {
+			for _, item := range slice {
+				if !yield(item) {
+					return
 				}
-			}()
-			return ch
-		},
-		pipeline: []func(<-chan T) <-chan T{},
-	}
+			}
+		}
+	})
+}
+
+// Generate creates a stream from a generator that returns false to end the stream.
+// The generator receives the context bound to the stream, so a generator that
+// blocks can stop as soon as the stream is cancelled.
+func Generate[T any](generator func(ctx context.Context) (T, bool)) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for {
+			item, ok := generator(ctx)
+			if !ok || !yield(item) {
+				return
+			}
+		}
+	})
 }
@@ -0,0 +1,202 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrBufferOverflow is reported when a buffer with the OverflowError policy is full
+var ErrBufferOverflow = errors.New("fp: stream buffer overflow")
+
+// OverflowPolicy defines what a buffered stage does when its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the producer wait for free space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the incoming item
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered item to make room
+	OverflowDropOldest
+	// OverflowSample replaces the most recently buffered item with the incoming one,
+	// so a burst is represented by its latest value
+	OverflowSample
+	// OverflowError ends the stream after the buffered items with ErrBufferOverflow.
+	// A TryStream reports it as a failed item, a Stream through Err.
+	OverflowError
+)
+
+// BufferStats counts what happened inside a buffered stage.
+// It is safe to read while the stream is running.
+type BufferStats struct {
+	received atomic.Int64
+	emitted  atomic.Int64
+	dropped  atomic.Int64
+	err      atomic.Pointer[error]
+}
+
+// Received returns the number of items taken from upstream
+func (bs *BufferStats) Received() int64 {
+	return bs.received.Load()
+}
+
+// Emitted returns the number of items passed downstream
+func (bs *BufferStats) Emitted() int64 {
+	return bs.emitted.Load()
+}
+
+// Dropped returns the number of items discarded by the overflow policy
+func (bs *BufferStats) Dropped() int64 {
+	return bs.dropped.Load()
+}
+
+// Err returns ErrBufferOverflow if the stream was ended by the OverflowError policy
+func (bs *BufferStats) Err() error {
+	if err := bs.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// BufferWithPolicy buffers the stream like Buffer and applies the policy when
+// the buffer is full. Stats may be nil.
+// An overflow with the OverflowError policy stops the run and Err reports it.
+func (s *Stream[T]) BufferWithPolicy(size int, policy OverflowPolicy, stats *BufferStats) *Stream[T] {
+	if stats == nil {
+		stats = &BufferStats{}
+	}
+	if size <= 0 {
+		size = 1
+	}
+
+	if policy == OverflowBlock {
+		return pipe(s, "BufferWithPolicy", func(input iter.Seq[T]) iter.Seq[T] {
+			return func(yield func(T) bool) {
+				done := make(chan struct{})
+				received := MapSeq(input, func(item T) T {
+					stats.received.Add(1)
+					return item
+				})
+				drain(pump(received, size, done), done, func(item T) bool {
+					stats.emitted.Add(1)
+					return yield(item)
+				})
+			}
+		})
+	}
+
+	return pipeCtx(s, "BufferWithPolicy", overflowBuffer(size, policy, stats, func(ctx context.Context, _ func(T) bool) {
+		stopRun(ctx, ErrBufferOverflow)
+	}))
+}
+
+// BufferWithPolicy buffers the stream like Stream.BufferWithPolicy.
+// With the OverflowError policy an overflow ends the stream with a failed item
+// holding ErrBufferOverflow, so terminals report it according to the error policy.
+func (ts *TryStream[T]) BufferWithPolicy(size int, policy OverflowPolicy, stats *BufferStats) *TryStream[T] {
+	if policy != OverflowError {
+		return &TryStream[T]{
+			results: ts.results.BufferWithPolicy(size, policy, stats),
+			policy:  ts.policy,
+			sink:    ts.sink,
+		}
+	}
+	if stats == nil {
+		stats = &BufferStats{}
+	}
+
+	return tryPipeCtx(ts, "BufferWithPolicy", overflowBuffer(max(size, 1), policy, stats, func(_ context.Context, yield func(Result[T]) bool) {
+		yield(Err[T](ErrBufferOverflow))
+	}))
+}
+
+// overflowBuffer returns a buffered stage that applies a policy other than
+// OverflowBlock. After an overflow ends the stage, the buffered items are
+// emitted and then overflowed is called.
+func overflowBuffer[T any](size int, policy OverflowPolicy, stats *BufferStats, overflowed func(ctx context.Context, yield func(T) bool)) func(context.Context, iter.Seq[T]) iter.Seq[T] {
+	return func(ctx context.Context, input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			var mu sync.Mutex
+			var queue []T
+			closed, failed := false, false
+			done := make(chan struct{})
+			ready := make(chan struct{}, 1)
+			defer close(done)
+
+			signal := func() {
+				select {
+				case ready <- struct{}{}:
+				default:
+				}
+			}
+
+			go func() {
+				defer func() {
+					mu.Lock()
+					closed = true
+					mu.Unlock()
+					signal()
+				}()
+
+				for item := range input {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					stats.received.Add(1)
+
+					mu.Lock()
+					overflow := len(queue) >= size
+					if !overflow {
+						queue = append(queue, item)
+					} else {
+						switch policy {
+						case OverflowDropNewest:
+						case OverflowDropOldest:
+							queue = append(queue[1:], item)
+						case OverflowSample:
+							queue[len(queue)-1] = item
+						case OverflowError:
+							failed = true
+							mu.Unlock()
+							err := ErrBufferOverflow
+							stats.err.Store(&err)
+							return
+						}
+						stats.dropped.Add(1)
+					}
+					mu.Unlock()
+					signal()
+				}
+			}()
+
+			for {
+				mu.Lock()
+				if len(queue) > 0 {
+					item := queue[0]
+					queue = queue[1:]
+					mu.Unlock()
+					stats.emitted.Add(1)
+					if !yield(item) {
+						return
+					}
+					continue
+				}
+				finished, overflow := closed, failed
+				mu.Unlock()
+
+				if finished {
+					if overflow {
+						overflowed(ctx, yield)
+					}
+					return
+				}
+				<-ready
+			}
+		}
+	}
+}
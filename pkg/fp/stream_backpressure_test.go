@@ -0,0 +1,79 @@
+package fp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForOverflow blocks until the buffer has overflowed, so a consumer calling it
+// is slow enough for any fast producer
+func waitForOverflow(t *testing.T, stats *BufferStats) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for stats.Err() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the buffer did not overflow")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTryStreamBufferOverflowError(t *testing.T) {
+	checkNoLeaks(t)
+	var stats BufferStats
+	var got []int
+	err := NewTryStream(RangeStream(0, 100)).
+		BufferWithPolicy(2, OverflowError, &stats).
+		ForEach(func(x int) {
+			waitForOverflow(t, &stats)
+			got = append(got, x)
+		})
+
+	if !errors.Is(err, ErrBufferOverflow) {
+		t.Fatalf("got error %v, want ErrBufferOverflow", err)
+	}
+	if len(got) == 0 || len(got) >= 100 {
+		t.Fatalf("got %d items before the overflow", len(got))
+	}
+}
+
+func TestStreamBufferOverflowError(t *testing.T) {
+	checkNoLeaks(t)
+	var stats BufferStats
+	s := RangeStream(0, 100).
+		BufferWithPolicy(2, OverflowError, &stats).
+		Peek(func(int) { waitForOverflow(t, &stats) })
+
+	got := s.Collect()
+	if len(got) == 0 || len(got) >= 100 || !errors.Is(s.Err(), ErrBufferOverflow) {
+		t.Fatalf("got %d items and %v", len(got), s.Err())
+	}
+	if got := RangeStream(0, 100).BufferWithPolicy(200, OverflowError, nil); got.Count() != 100 || got.Err() != nil {
+		t.Fatalf("a buffer that did not overflow reports %v", got.Err())
+	}
+}
+
+func TestTryStreamBufferWithoutOverflow(t *testing.T) {
+	got, err := NewTryStream(RangeStream(0, 100)).BufferWithPolicy(200, OverflowError, nil).Collect()
+	if err != nil || len(got) != 100 {
+		t.Fatalf("got %d items and %v", len(got), err)
+	}
+}
+
+func TestStreamBufferDropNewest(t *testing.T) {
+	checkNoLeaks(t)
+	var stats BufferStats
+	got := RangeStream(0, 100).
+		BufferWithPolicy(2, OverflowDropNewest, &stats).
+		Peek(func(int) {
+			for stats.Received() < 100 {
+				time.Sleep(time.Millisecond)
+			}
+		}).
+		Collect()
+
+	if int64(len(got))+stats.Dropped() != 100 || stats.Emitted() != int64(len(got)) {
+		t.Fatalf("got %d items, dropped %d, emitted %d", len(got), stats.Dropped(), stats.Emitted())
+	}
+}
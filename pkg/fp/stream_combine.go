@@ -0,0 +1,167 @@
+package fp
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// MergeStreams combines streams into one, emitting items in the order they arrive.
+// Every stream is consumed in its own goroutine.
+func MergeStreams[T any](streams ...*Stream[T]) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+		merged := make(chan T)
+
+		for _, s := range streams {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range s.open(ctx) {
+					if !send(done, merged, item) {
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(merged)
+		}()
+
+		drain(merged, done, yield)
+	})
+}
+
+// ConcatStreams combines streams into one, consuming them one after another
+func ConcatStreams[T any](streams ...*Stream[T]) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		for _, s := range streams {
+			for item := range s.open(ctx) {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// ZipStreams is an alias of StreamZip named like MergeStreams and ConcatStreams
+func ZipStreams[T, R any](s1 *Stream[T], s2 *Stream[R]) *Stream[Pair[T, R]] {
+	return StreamZip(s1, s2)
+}
+
+// InterleaveStreams takes items from the streams in round-robin order.
+// Exhausted streams are skipped until all of them are done.
+func InterleaveStreams[T any](streams ...*Stream[T]) *Stream[T] {
+	return newStream(func(ctx context.Context, yield func(T) bool) {
+		type puller struct {
+			next func() (T, bool)
+			stop func()
+		}
+
+		active := make([]puller, 0, len(streams))
+		for _, s := range streams {
+			next, stop := iter.Pull(s.open(ctx))
+			defer stop()
+			active = append(active, puller{next: next, stop: stop})
+		}
+
+		for len(active) > 0 {
+			remaining := active[:0]
+			for _, p := range active {
+				item, ok := p.next()
+				if !ok {
+					p.stop()
+					continue
+				}
+				if !yield(item) {
+					return
+				}
+				remaining = append(remaining, p)
+			}
+			active = remaining
+		}
+	})
+}
+
+// Tee splits the stream into n independent streams.
+// The source runs once, when the first branch is consumed, and every item is
+// delivered to each branch through its own buffer of the given size. A branch
+// that stops early is detached; a branch that is never consumed holds the
+// others back once its buffer is full. Each branch can be consumed only once.
+func (s *Stream[T]) Tee(n, buffer int) []*Stream[T] {
+	var once sync.Once
+	channels := make([]chan T, n)
+	detached := make([]chan struct{}, n)
+	for i := range channels {
+		channels[i] = make(chan T, buffer)
+		detached[i] = make(chan struct{})
+	}
+
+	start := func() {
+		go func() {
+			defer func() {
+				for _, ch := range channels {
+					close(ch)
+				}
+			}()
+
+			active := n
+			attached := make([]bool, n)
+			for i := range attached {
+				attached[i] = true
+			}
+
+			for item := range s.run() {
+				for i, ch := range channels {
+					if attached[i] && !send(detached[i], ch, item) {
+						attached[i] = false
+						active--
+					}
+				}
+				if active == 0 {
+					return
+				}
+			}
+		}()
+	}
+
+	branches := make([]*Stream[T], n)
+	for i := range branches {
+		var detach sync.Once
+		branches[i] = newStream(singleUse("Tee", func(ctx context.Context, yield func(T) bool) {
+			once.Do(start)
+			defer detach.Do(func() { close(detached[i]) })
+			for {
+				select {
+				case item, ok := <-channels[i]:
+					if !ok || !yield(item) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}))
+	}
+	return branches
+}
+
+// Broadcast runs every consumer concurrently on its own branch of the stream
+// and waits until all of them return
+func (s *Stream[T]) Broadcast(buffer int, consumers ...func(*Stream[T])) {
+	branches := s.Tee(len(consumers), buffer)
+
+	var wg sync.WaitGroup
+	for i, consumer := range consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumer(branches[i])
+		}()
+	}
+	wg.Wait()
+}
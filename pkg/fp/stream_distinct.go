@@ -0,0 +1,127 @@
+package fp
+
+import (
+	"container/list"
+	"hash/maphash"
+	"iter"
+	"math"
+)
+
+// DistinctBy removes duplicates from the stream by key.
+// Seen keys are kept in a typed set, so memory grows with the number of distinct keys.
+func DistinctBy[T any, K comparable](s *Stream[T], keyExtractor KeyExtractor[T, K]) *Stream[T] {
+	return pipe(s, "DistinctBy", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			seen := make(map[K]struct{})
+			for item := range input {
+				key := keyExtractor(item)
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// DistinctLRU removes duplicates by key, remembering only the capacity most recently seen keys.
+// A duplicate that reappears after its key was evicted is emitted again.
+func DistinctLRU[T any, K comparable](s *Stream[T], keyExtractor KeyExtractor[T, K], capacity int) *Stream[T] {
+	return pipe(s, "DistinctLRU", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			if capacity <= 0 {
+				capacity = 1
+			}
+
+			recent := list.New()
+			seen := make(map[K]*list.Element, capacity)
+			for item := range input {
+				key := keyExtractor(item)
+				if elem, ok := seen[key]; ok {
+					recent.MoveToFront(elem)
+					continue
+				}
+
+				seen[key] = recent.PushFront(key)
+				if recent.Len() > capacity {
+					oldest := recent.Back()
+					recent.Remove(oldest)
+					delete(seen, oldest.Value.(K))
+				}
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// DistinctBloom removes duplicates by key using a Bloom filter sized for
+// expectedItems keys at the given false positive rate. Memory stays fixed,
+// duplicates are never emitted, but a small share of unique items may be
+// dropped as false positives.
+func DistinctBloom[T any, K comparable](s *Stream[T], keyExtractor KeyExtractor[T, K], expectedItems int, falsePositiveRate float64) *Stream[T] {
+	return pipe(s, "DistinctBloom", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			filter := newBloomFilter[K](expectedItems, falsePositiveRate)
+			for item := range input {
+				if filter.testAndAdd(keyExtractor(item)) {
+					continue
+				}
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// bloomFilter is a Bloom filter over comparable keys using double hashing
+type bloomFilter[K comparable] struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+	seed1  maphash.Seed
+	seed2  maphash.Seed
+}
+
+// newBloomFilter sizes the filter for n keys at false positive rate p
+func newBloomFilter[K comparable](n int, p float64) *bloomFilter[K] {
+	if n <= 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	size := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Max(1, math.Round(float64(size)/float64(n)*math.Ln2)))
+
+	return &bloomFilter[K]{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+		seed1:  maphash.MakeSeed(),
+		seed2:  maphash.MakeSeed(),
+	}
+}
+
+// testAndAdd adds the key and reports whether it was probably present before
+func (b *bloomFilter[K]) testAndAdd(key K) bool {
+	h1 := maphash.Comparable(b.seed1, key)
+	h2 := maphash.Comparable(b.seed2, key) | 1
+
+	present := true
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			present = false
+			b.bits[word] |= mask
+		}
+	}
+	return present
+}
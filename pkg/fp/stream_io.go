@@ -0,0 +1,369 @@
+package fp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxLineSize is the longest line StreamLines and StreamJSONL accept, in bytes
+const DefaultMaxLineSize = 1 << 20
+
+// ErrLineTooLong is reported for a line longer than the limit of the stream reading it
+var ErrLineTooLong = errors.New("fp: line too long")
+
+// StreamLines creates a stream of lines read from the reader.
+// Lines longer than DefaultMaxLineSize become error items and are skipped,
+// a read error is emitted as the last item of the stream.
+// The stream can be consumed only once.
+func StreamLines(r io.Reader) *TryStream[string] {
+	return StreamLinesWithLimit(r, DefaultMaxLineSize)
+}
+
+// StreamLinesWithLimit creates a stream of lines read from the reader,
+// where lines longer than maxLine bytes become ErrLineTooLong error items.
+// The stream can be consumed only once.
+func StreamLinesWithLimit(r io.Reader, maxLine int) *TryStream[string] {
+	return FromResults(newStream(singleUse("reader", func(ctx context.Context, yield func(Result[string]) bool) {
+		readLines(r, maxLine, func(_ int, text []byte, err error) bool {
+			if err != nil {
+				return yield(Err[string](err))
+			}
+			return yield(Ok(string(text)))
+		})
+	})))
+}
+
+// StreamJSONL creates a stream of values decoded from JSON Lines.
+// Empty lines are skipped, a line that fails to decode or is longer than
+// DefaultMaxLineSize becomes an error item and the stream goes on.
+// The stream can be consumed only once.
+func StreamJSONL[T any](r io.Reader) *TryStream[T] {
+	return StreamJSONLWithLimit[T](r, DefaultMaxLineSize)
+}
+
+// StreamJSONLWithLimit creates a stream of values decoded from JSON Lines,
+// where lines longer than maxLine bytes become ErrLineTooLong error items.
+// The stream can be consumed only once.
+func StreamJSONLWithLimit[T any](r io.Reader, maxLine int) *TryStream[T] {
+	return FromResults(newStream(singleUse("reader", func(ctx context.Context, yield func(Result[T]) bool) {
+		readLines(r, maxLine, func(line int, text []byte, err error) bool {
+			if err != nil {
+				return yield(Err[T](err))
+			}
+			if len(bytes.TrimSpace(text)) == 0 {
+				return true
+			}
+
+			var value T
+			if err := json.Unmarshal(text, &value); err != nil {
+				return yield(Err[T](fmt.Errorf("line %d: %w", line, err)))
+			}
+			return yield(Ok(value))
+		})
+	})))
+}
+
+// readLines calls fn with every line of the reader without its line ending.
+// A line longer than maxLine is skipped and reported as ErrLineTooLong,
+// a read error other than io.EOF is reported last. The text is only valid
+// until fn returns.
+func readLines(r io.Reader, maxLine int, fn func(line int, text []byte, err error) bool) {
+	reader := bufio.NewReader(r)
+	var text []byte
+	line := 0
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull && err != io.EOF {
+			fn(line+1, nil, err)
+			return
+		}
+		// Leave room for the line ending, it is cut off below
+		if !tooLong && len(text)+len(chunk) <= maxLine+2 {
+			text = append(text, chunk...)
+		} else {
+			tooLong, text = true, text[:0]
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(text) == 0 && !tooLong {
+			return
+		}
+
+		line++
+		text = bytes.TrimSuffix(bytes.TrimSuffix(text, []byte("\n")), []byte("\r"))
+		var ok bool
+		if tooLong || len(text) > maxLine {
+			ok = fn(line, nil, fmt.Errorf("line %d: %w", line, ErrLineTooLong))
+		} else {
+			ok = fn(line, text, nil)
+		}
+		if !ok || err == io.EOF {
+			return
+		}
+		tooLong, text = false, text[:0]
+	}
+}
+
+// StreamCSV creates a stream of CSV records.
+// A malformed record becomes an error item, other read errors end the stream.
+// The stream can be consumed only once.
+func StreamCSV(r io.Reader) *TryStream[[]string] {
+	return FromResults(newStream(singleUse("reader", func(ctx context.Context, yield func(Result[[]string]) bool) {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if !yield(Err[[]string](err)) || !errors.As(err, &parseErr) {
+					return
+				}
+				continue
+			}
+			if !yield(Ok(record)) {
+				return
+			}
+		}
+	})))
+}
+
+// StreamCSVAs creates a stream of structs from CSV with a header row.
+// Columns are matched to fields by the `csv` tag or, without a tag, by the
+// field name ignoring case. Unknown columns are ignored.
+// The stream can be consumed only once.
+func StreamCSVAs[T any](r io.Reader) *TryStream[T] {
+	return FromResults(newStream(singleUse("reader", func(ctx context.Context, yield func(Result[T]) bool) {
+		var columns []int
+		line := 0
+		for res := range StreamCSV(r).Results().open(ctx) {
+			line++
+			if res.IsErr() {
+				if !yield(Err[T](res.err)) {
+					return
+				}
+				continue
+			}
+
+			if columns == nil {
+				var err error
+				if columns, err = csvColumns(reflect.TypeFor[T](), res.value); err != nil {
+					yield(Err[T](err))
+					return
+				}
+				continue
+			}
+
+			var value T
+			if err := csvDecode(reflect.ValueOf(&value).Elem(), columns, res.value); err != nil {
+				if !yield(Err[T](fmt.Errorf("record %d: %w", line, err))) {
+					return
+				}
+				continue
+			}
+			if !yield(Ok(value)) {
+				return
+			}
+		}
+	})))
+}
+
+// csvColumns maps every header column to a struct field index, -1 for unknown columns
+func csvColumns(t reflect.Type, header []string) ([]int, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: %v is not a struct", t)
+	}
+
+	columns := make([]int, len(header))
+	for i, name := range header {
+		columns[i] = -1
+		for j := 0; j < t.NumField(); j++ {
+			field := t.Field(j)
+			if !field.IsExported() {
+				continue
+			}
+			tag := field.Tag.Get("csv")
+			if tag == "-" {
+				continue
+			}
+			if tag == name || (tag == "" && strings.EqualFold(field.Name, strings.TrimSpace(name))) {
+				columns[i] = j
+				break
+			}
+		}
+	}
+	return columns, nil
+}
+
+// csvDecode fills the struct fields from a record
+func csvDecode(value reflect.Value, columns []int, record []string) error {
+	for i, text := range record {
+		if i >= len(columns) || columns[i] < 0 {
+			continue
+		}
+		field := value.Field(columns[i])
+		if err := setFromString(field, text); err != nil {
+			return fmt.Errorf("field %s: %w", value.Type().Field(columns[i]).Name, err)
+		}
+	}
+	return nil
+}
+
+// setFromString parses the text into a value of the field's type
+func setFromString(field reflect.Value, text string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
+
+// WriteLines writes every item of the stream as a line.
+// If the stream is stopped early, such as by a cancelled context, the error that stopped it is returned.
+func WriteLines(s *Stream[string], w io.Writer) error {
+	write, flush := lineWriter(w)
+	return flushed(writeTo(s, write), flush)
+}
+
+// TryWriteLines writes every successful item of the stream as a line.
+// Failed items are handled by the error policy of the stream, and the error
+// the policy reports is returned once the items before it are written.
+func TryWriteLines(ts *TryStream[string], w io.Writer) error {
+	write, flush := lineWriter(w)
+	return flushed(tryWriteTo(ts, write), flush)
+}
+
+// WriteJSONL writes every item of the stream as a JSON line.
+// If the stream is stopped early, such as by a cancelled context, the error that stopped it is returned.
+func WriteJSONL[T any](s *Stream[T], w io.Writer) error {
+	write, flush := jsonlWriter[T](w)
+	return flushed(writeTo(s, write), flush)
+}
+
+// TryWriteJSONL writes every successful item of the stream as a JSON line.
+// Failed items are handled by the error policy of the stream, and the error
+// the policy reports is returned once the items before it are written.
+func TryWriteJSONL[T any](ts *TryStream[T], w io.Writer) error {
+	write, flush := jsonlWriter[T](w)
+	return flushed(tryWriteTo(ts, write), flush)
+}
+
+// WriteCSV writes every item of the stream as a CSV record.
+// If the stream is stopped early, such as by a cancelled context, the error that stopped it is returned.
+func WriteCSV(s *Stream[[]string], w io.Writer) error {
+	write, flush := csvWriter(w)
+	return flushed(writeTo(s, write), flush)
+}
+
+// TryWriteCSV writes every successful item of the stream as a CSV record.
+// Failed items are handled by the error policy of the stream, and the error
+// the policy reports is returned once the items before it are written.
+func TryWriteCSV(ts *TryStream[[]string], w io.Writer) error {
+	write, flush := csvWriter(w)
+	return flushed(tryWriteTo(ts, write), flush)
+}
+
+// writeTo runs the stream into write until a write fails, and reports a run
+// that stopped early so truncated output never looks complete
+func writeTo[T any](s *Stream[T], write func(T) error) error {
+	for item := range s.run() {
+		if err := write(item); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// tryWriteTo runs the successful items of the stream into write until a write fails
+func tryWriteTo[T any](ts *TryStream[T], write func(T) error) error {
+	var writeErr error
+	err := ts.run(func(item T) bool {
+		writeErr = write(item)
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// flushed flushes what was written and returns the error that ended the writing, if any
+func flushed(err error, flush func() error) error {
+	if flushErr := flush(); err == nil {
+		return flushErr
+	}
+	return err
+}
+
+// lineWriter returns a function that writes a line to w and one that flushes it
+func lineWriter(w io.Writer) (func(string) error, func() error) {
+	writer := bufio.NewWriter(w)
+	return func(line string) error {
+		if _, err := writer.WriteString(line); err != nil {
+			return err
+		}
+		return writer.WriteByte('\n')
+	}, writer.Flush
+}
+
+// jsonlWriter returns a function that writes a JSON line to w and one that flushes it
+func jsonlWriter[T any](w io.Writer) (func(T) error, func() error) {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	return func(item T) error {
+		return encoder.Encode(item)
+	}, writer.Flush
+}
+
+// csvWriter returns a function that writes a CSV record to w and one that flushes it
+func csvWriter(w io.Writer) (func([]string) error, func() error) {
+	writer := csv.NewWriter(w)
+	return writer.Write, func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+}
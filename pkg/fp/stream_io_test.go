@@ -0,0 +1,180 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStreamLines(t *testing.T) {
+	got, err := StreamLines(strings.NewReader("a\r\n\nb\nc")).Collect()
+	if err != nil || !slices.Equal(got, []string{"a", "", "b", "c"}) {
+		t.Fatalf("got %q and %v", got, err)
+	}
+}
+
+func TestStreamLinesSkipsLongLines(t *testing.T) {
+	long := strings.Repeat("x", 100_000)
+	input := "a\n" + long + "\nb\n"
+
+	got, err := StreamLinesWithLimit(strings.NewReader(input), 10).WithPolicy(SkipErrors).Collect()
+	if !slices.Equal(got, []string{"a", "b"}) || !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("got %q and %v", got, err)
+	}
+	if got, err := StreamLines(strings.NewReader(input)).Count(); got != 3 || err != nil {
+		t.Fatalf("counted %d lines and %v under the default limit", got, err)
+	}
+}
+
+func TestStreamJSONLGoesOnAfterBadLines(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	long := `{"name": "` + strings.Repeat("x", 100) + `"}`
+	input := `{"name": "ann"}` + "\n\n" + `{"name": ` + "\n" + long + "\n" + `{"name": "bob"}`
+
+	var errs []error
+	got, err := StreamJSONLWithLimit[user](strings.NewReader(input), 50).WithDeadLetter(func(err error) {
+		errs = append(errs, err)
+	}).Collect()
+	if err != nil || !slices.Equal(got, []user{{"ann"}, {"bob"}}) {
+		t.Fatalf("got %v and %v", got, err)
+	}
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "line 3:") || !errors.Is(errs[1], ErrLineTooLong) {
+		t.Fatalf("got errors %v", errs)
+	}
+}
+
+func TestTryWriteLinesCopiesLazily(t *testing.T) {
+	var out strings.Builder
+	err := TryWriteLines(StreamLines(strings.NewReader("a\n\nb\n")).Filter(Strings.IsNotEmpty), &out)
+	if err != nil || out.String() != "a\nb\n" {
+		t.Fatalf("wrote %q and got %v", out.String(), err)
+	}
+}
+
+func TestTryWriteJSONLAppliesThePolicy(t *testing.T) {
+	input := "1\nx\n3\n"
+
+	var out strings.Builder
+	err := TryWriteJSONL(StreamJSONL[int](strings.NewReader(input)), &out)
+	if err == nil || out.String() != "1\n" {
+		t.Fatalf("FailFast wrote %q and got %v", out.String(), err)
+	}
+
+	out.Reset()
+	err = TryWriteJSONL(StreamJSONL[int](strings.NewReader(input)).WithPolicy(SkipErrors), &out)
+	if err == nil || out.String() != "1\n3\n" {
+		t.Fatalf("SkipErrors wrote %q and got %v", out.String(), err)
+	}
+}
+
+func TestTryWriteCSVRoundTrip(t *testing.T) {
+	input := "name,age\nann,\"3,5\"\n"
+	var out strings.Builder
+	if err := TryWriteCSV(StreamCSV(strings.NewReader(input)), &out); err != nil || out.String() != input {
+		t.Fatalf("wrote %q and got %v", out.String(), err)
+	}
+}
+
+// level decodes itself from text
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type csvRow struct {
+	Name    string
+	Age     int8
+	Count   uint
+	Score   float64
+	Active  bool
+	Level   level  `csv:"lvl"`
+	Ignored string `csv:"-"`
+	hidden  string
+}
+
+func TestStreamCSVAs(t *testing.T) {
+	input := "name, AGE ,count,score,active,lvl,ignored,hidden,extra\n" +
+		"ann,30,2,1.5,true,high,x,y,z\n" +
+		"bob,300,1,2,false,low,,,\n" +
+		"cid,40,1,2,yes,low,,,\n" +
+		"dan,50,1,2,false,mid,,,\n" +
+		"eve,20\n"
+
+	var errs []error
+	got, err := StreamCSVAs[csvRow](strings.NewReader(input)).WithDeadLetter(func(err error) {
+		errs = append(errs, err)
+	}).Collect()
+	want := []csvRow{
+		{Name: "ann", Age: 30, Count: 2, Score: 1.5, Active: true, Level: 2},
+		{Name: "eve", Age: 20},
+	}
+	if err != nil || !slices.Equal(got, want) {
+		t.Fatalf("got %+v and %v", got, err)
+	}
+
+	wantErrs := []string{"record 3: field Age", "record 4: field Active", "record 5: field Level"}
+	if len(errs) != len(wantErrs) {
+		t.Fatalf("got errors %v", errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), wantErrs[i]) {
+			t.Errorf("error %d is %q, want it to start with %q", i, err, wantErrs[i])
+		}
+	}
+}
+
+func TestStreamCSVAsRejectsNonStructs(t *testing.T) {
+	if _, err := StreamCSVAs[int](strings.NewReader("a\n1\n")).Collect(); err == nil || !strings.Contains(err.Error(), "not a struct") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestStreamCSVReportsMalformedRecords(t *testing.T) {
+	got, err := StreamCSV(strings.NewReader("a,b\n\"x\ny,z\n")).WithPolicy(SkipErrors).Collect()
+	if err == nil || len(got) != 1 {
+		t.Fatalf("got %q and %v", got, err)
+	}
+}
+
+func TestSinksReportCancelledStreams(t *testing.T) {
+	sinks := map[string]func(*Stream[int], io.Writer) error{
+		"WriteLines": func(s *Stream[int], w io.Writer) error {
+			return WriteLines(StreamMap(s, strconv.Itoa), w)
+		},
+		"WriteJSONL": WriteJSONL[int],
+		"WriteCSV": func(s *Stream[int], w io.Writer) error {
+			return WriteCSV(StreamMap(s, func(x int) []string { return []string{strconv.Itoa(x)} }), w)
+		},
+	}
+	for name, sink := range sinks {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := RangeStream(0, 10).Peek(func(x int) {
+				if x == 2 {
+					cancel()
+				}
+			}).WithContext(ctx)
+
+			var out strings.Builder
+			if err := sink(s, &out); !errors.Is(err, context.Canceled) || out.Len() == 0 {
+				t.Fatalf("wrote %q and got %v from a cancelled stream", out.String(), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package fp
+
+import (
+	"context"
+	"expvar"
+	"iter"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamObserver receives events from every stage of an observed stream.
+// Stages may run concurrently, so implementations must be safe for concurrent use.
+type StreamObserver interface {
+	// ItemIn is called when the stage receives an item, wait is the time spent waiting for it
+	ItemIn(stage string, wait time.Duration)
+	// ItemOut is called after the stage emits an item, blocked is the time the downstream took to accept it
+	ItemOut(stage string, blocked time.Duration)
+	// Error is called when the stage emits a failed Result.
+	// Failures passed through unchanged from upstream are not reported again.
+	Error(stage string, err error)
+	// StageDone is called once the stage stops, with its totals for the run
+	StageDone(stage string, stats StageStats)
+}
+
+// StageStats are the totals of a single stage for one run of the stream
+type StageStats struct {
+	ItemsIn  int64
+	ItemsOut int64
+	Errors   int64
+	// Busy is the time spent in the stage itself, including the user function.
+	// For stages that run goroutines it is an estimate.
+	Busy time.Duration
+	// Waiting is the time spent waiting for items from upstream
+	Waiting time.Duration
+	// Blocked is the time spent waiting for the downstream to accept items
+	Blocked time.Duration
+	// Elapsed is the wall time from the start of the stage until it stopped
+	Elapsed time.Duration
+}
+
+type (
+	observerKey  struct{}
+	stageNameKey struct{}
+)
+
+// WithObserver binds an observer to the whole stream, replacing any observer bound earlier
+func (s *Stream[T]) WithObserver(observer StreamObserver) *Stream[T] {
+	return &Stream[T]{
+		source:   s.source,
+		ctx:      s.ctx,
+		observer: observer,
+	}
+}
+
+// Named names the last stage of the stream in observer reports.
+// Without a name a stage is reported under the name of the operator that created it.
+func (s *Stream[T]) Named(name string) *Stream[T] {
+	return &Stream[T]{
+		source: func(ctx context.Context) iter.Seq[T] {
+			return s.source(context.WithValue(ctx, stageNameKey{}, name))
+		},
+		ctx:      s.ctx,
+		observer: s.observer,
+	}
+}
+
+// WithObserver binds an observer to the whole stream
+func (ts *TryStream[T]) WithObserver(observer StreamObserver) *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results.WithObserver(observer),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// Named names the last stage of the stream in observer reports
+func (ts *TryStream[T]) Named(name string) *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results.Named(name),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// stageNameFrom returns the name given with Named, or the default one, and a
+// context without the name so it does not reach the upstream stages
+func stageNameFrom(ctx context.Context, defaultName string) (string, context.Context) {
+	if name, ok := ctx.Value(stageNameKey{}).(string); ok && name != "" {
+		return name, context.WithValue(ctx, stageNameKey{}, "")
+	}
+	return defaultName, ctx
+}
+
+// stageProbe accumulates the events of one stage run
+type stageProbe struct {
+	observer StreamObserver
+	name     string
+	itemsIn  atomic.Int64
+	itemsOut atomic.Int64
+	errors   atomic.Int64
+	passed   atomic.Int64
+	waiting  atomic.Int64
+	blocked  atomic.Int64
+}
+
+// failure returns the error of a failed Result item
+func failure(item any) error {
+	if res, ok := item.(interface {
+		IsErr() bool
+		Error() error
+	}); ok && res.IsErr() {
+		return res.Error()
+	}
+	return nil
+}
+
+// takePassed consumes one failure received from upstream, if any
+func (p *stageProbe) takePassed() bool {
+	for {
+		passed := p.passed.Load()
+		if passed == 0 {
+			return false
+		}
+		if p.passed.CompareAndSwap(passed, passed-1) {
+			return true
+		}
+	}
+}
+
+// stats returns the totals of the run
+func (p *stageProbe) stats(elapsed time.Duration) StageStats {
+	stats := StageStats{
+		ItemsIn:  p.itemsIn.Load(),
+		ItemsOut: p.itemsOut.Load(),
+		Errors:   p.errors.Load(),
+		Waiting:  time.Duration(p.waiting.Load()),
+		Blocked:  time.Duration(p.blocked.Load()),
+		Elapsed:  elapsed,
+	}
+	stats.Busy = max(elapsed-stats.Waiting-stats.Blocked, 0)
+	return stats
+}
+
+// observeInput reports the items a stage receives
+func observeInput[T any](p *stageProbe, input iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		start := time.Now()
+		for item := range input {
+			wait := time.Since(start)
+			p.itemsIn.Add(1)
+			p.waiting.Add(int64(wait))
+			if failure(item) != nil {
+				p.passed.Add(1)
+			}
+			p.observer.ItemIn(p.name, wait)
+			if !yield(item) {
+				return
+			}
+			start = time.Now()
+		}
+	}
+}
+
+// observeOutput reports the items a stage emits and its totals once it stops
+func observeOutput[R any](p *stageProbe, output iter.Seq[R]) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		start := time.Now()
+		defer func() {
+			p.observer.StageDone(p.name, p.stats(time.Since(start)))
+		}()
+
+		for item := range output {
+			if err := failure(item); err != nil && !p.takePassed() {
+				p.errors.Add(1)
+				p.observer.Error(p.name, err)
+			}
+			p.itemsOut.Add(1)
+
+			sent := time.Now()
+			ok := yield(item)
+			blocked := time.Since(sent)
+			p.blocked.Add(int64(blocked))
+			p.observer.ItemOut(p.name, blocked)
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// slogObserver logs stage totals and errors
+type slogObserver struct {
+	logger *slog.Logger
+}
+
+// SlogObserver returns an observer that logs failed items at the Warn level
+// and the totals of every stage at the Info level
+func SlogObserver(logger *slog.Logger) StreamObserver {
+	return slogObserver{logger: logger}
+}
+
+func (slogObserver) ItemIn(string, time.Duration)  {}
+func (slogObserver) ItemOut(string, time.Duration) {}
+
+// Error logs a failed item
+func (o slogObserver) Error(stage string, err error) {
+	o.logger.Warn("stream stage error", "stage", stage, "error", err)
+}
+
+// StageDone logs the totals of the stage
+func (o slogObserver) StageDone(stage string, stats StageStats) {
+	o.logger.Info("stream stage done",
+		"stage", stage,
+		"items_in", stats.ItemsIn,
+		"items_out", stats.ItemsOut,
+		"errors", stats.Errors,
+		"busy", stats.Busy,
+		"waiting", stats.Waiting,
+		"blocked", stats.Blocked,
+		"elapsed", stats.Elapsed,
+	)
+}
+
+// expvarMu guards publishing of expvar maps
+var expvarMu sync.Mutex
+
+// expvarObserver publishes counters per stage
+type expvarObserver struct {
+	mu     sync.Mutex
+	stages *expvar.Map
+}
+
+// ExpvarObserver returns an observer that publishes counters for every stage
+// under the given expvar name: items_in, items_out, errors, runs, and the
+// wait_ns, blocked_ns and busy_ns timings. Observers with the same name share the counters.
+func ExpvarObserver(name string) StreamObserver {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	stages, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		stages = expvar.NewMap(name)
+	}
+	return &expvarObserver{stages: stages}
+}
+
+// stage returns the counters of the stage
+func (o *expvarObserver) stage(name string) *expvar.Map {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if vars, ok := o.stages.Get(name).(*expvar.Map); ok {
+		return vars
+	}
+	vars := new(expvar.Map).Init()
+	o.stages.Set(name, vars)
+	return vars
+}
+
+// ItemIn counts a received item
+func (o *expvarObserver) ItemIn(stage string, wait time.Duration) {
+	vars := o.stage(stage)
+	vars.Add("items_in", 1)
+	vars.Add("wait_ns", int64(wait))
+}
+
+// ItemOut counts an emitted item
+func (o *expvarObserver) ItemOut(stage string, blocked time.Duration) {
+	vars := o.stage(stage)
+	vars.Add("items_out", 1)
+	vars.Add("blocked_ns", int64(blocked))
+}
+
+// Error counts a failed item
+func (o *expvarObserver) Error(stage string, _ error) {
+	o.stage(stage).Add("errors", 1)
+}
+
+// StageDone counts a finished run of the stage
+func (o *expvarObserver) StageDone(stage string, stats StageStats) {
+	vars := o.stage(stage)
+	vars.Add("runs", 1)
+	vars.Add("busy_ns", int64(stats.Busy))
+}
@@ -0,0 +1,66 @@
+package fp
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver records the totals and errors of every stage
+type recordingObserver struct {
+	mu     sync.Mutex
+	stages []string
+	stats  map[string]StageStats
+	errors map[string]int
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{stats: map[string]StageStats{}, errors: map[string]int{}}
+}
+
+func (o *recordingObserver) ItemIn(string, time.Duration)  {}
+func (o *recordingObserver) ItemOut(string, time.Duration) {}
+
+func (o *recordingObserver) Error(stage string, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errors[stage]++
+}
+
+func (o *recordingObserver) StageDone(stage string, stats StageStats) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stages = append(o.stages, stage)
+	o.stats[stage] = stats
+}
+
+func TestObserverStageNames(t *testing.T) {
+	observer := newRecordingObserver()
+	_, err := StreamMapErr(
+		RangeStream(0, 10).
+			Map(func(x int) int { return x + 1 }).
+			Filter(func(x int) bool { return x%2 == 0 }).Named("evens"),
+		func(x int) (int, error) {
+			if x == 4 {
+				return 0, errors.New("four")
+			}
+			return x, nil
+		},
+	).WithPolicy(SkipErrors).WithObserver(observer).Collect()
+	if err == nil {
+		t.Fatal("the failed item was not reported")
+	}
+
+	// Stages finish from the most upstream one
+	if want := []string{"Map", "evens", "NewTryStream", "StreamMapErr"}; !slices.Equal(observer.stages, want) {
+		t.Fatalf("got stages %v, want %v", observer.stages, want)
+	}
+	if stats := observer.stats["evens"]; stats.ItemsIn != 10 || stats.ItemsOut != 5 {
+		t.Fatalf("got %+v for the filter", stats)
+	}
+	if observer.errors["StreamMapErr"] != 1 {
+		t.Fatalf("got errors %v", observer.errors)
+	}
+}
@@ -0,0 +1,191 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrAttemptTimeout is reported when an attempt runs longer than RetryPolicy.Timeout
+var ErrAttemptTimeout = errors.New("fp: attempt timed out")
+
+// RetryPolicy configures how a per-item function is retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, values below 1 mean a single attempt
+	MaxAttempts int
+	// Backoff is the delay before the second attempt
+	Backoff time.Duration
+	// Multiplier grows the delay after every attempt, values below 1 keep it constant
+	Multiplier float64
+	// MaxBackoff caps the delay, zero means no cap
+	MaxBackoff time.Duration
+	// Jitter randomizes every delay by up to this fraction in either direction, from 0 to 1
+	Jitter float64
+	// Timeout limits a single attempt, zero means no limit.
+	// The context passed to the function is cancelled when the attempt times out.
+	Timeout time.Duration
+	// Retryable reports whether an error is worth another attempt, nil retries every error
+	Retryable func(error) bool
+	// Clock drives delays and timeouts, nil means SystemClock
+	Clock Clock
+}
+
+// Attempt describes a single call of the per-item function
+type Attempt struct {
+	// Number of the attempt, starting at 1
+	Number int
+	// Delay waited before the attempt
+	Delay time.Duration
+	// Duration of the call measured by the policy clock
+	Duration time.Duration
+	// Err returned by the call, nil for the successful attempt
+	Err error
+}
+
+// Retried is the result of a retried call together with its attempt history
+type Retried[R any] struct {
+	Result[R]
+	// Attempts made for the item, in order
+	Attempts []Attempt
+	// Fallback reports whether the value comes from a fallback after all attempts failed
+	Fallback bool
+}
+
+// Retry wraps the function so that failed calls are retried according to the policy.
+// When all attempts fail, the Result holds the last error.
+func Retry[T, R any](fn func(context.Context, T) (R, error), policy RetryPolicy) func(context.Context, T) Retried[R] {
+	clock := policy.Clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+	attempts := max(policy.MaxAttempts, 1)
+
+	return func(ctx context.Context, item T) Retried[R] {
+		var retried Retried[R]
+		var delay time.Duration
+		for n := 1; ; n++ {
+			start := clock.Now()
+			value, err := callAttempt(ctx, clock, policy.Timeout, fn, item)
+			retried.Attempts = append(retried.Attempts, Attempt{
+				Number:   n,
+				Delay:    delay,
+				Duration: clock.Now().Sub(start),
+				Err:      err,
+			})
+
+			if err == nil {
+				retried.Result = Ok(value)
+				return retried
+			}
+			if n == attempts || ctx.Err() != nil || (policy.Retryable != nil && !policy.Retryable(err)) {
+				retried.Result = Err[R](fmt.Errorf("attempt %d: %w", n, err))
+				return retried
+			}
+
+			delay = policy.delay(n)
+			select {
+			case <-clock.After(delay):
+			case <-ctx.Done():
+				retried.Result = Err[R](fmt.Errorf("attempt %d: %w", n, ctx.Err()))
+				return retried
+			}
+		}
+	}
+}
+
+// callAttempt runs a single attempt, bounded by the timeout
+func callAttempt[T, R any](ctx context.Context, clock Clock, timeout time.Duration, fn func(context.Context, T) (R, error), item T) (R, error) {
+	if timeout <= 0 {
+		return fn(ctx, item)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type outcome struct {
+		value R
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn(ctx, item)
+		done <- outcome{value: value, err: err}
+	}()
+
+	var zero R
+	select {
+	case out := <-done:
+		return out.value, out.err
+	case <-clock.After(timeout):
+		cancel(ErrAttemptTimeout)
+		return zero, ErrAttemptTimeout
+	case <-ctx.Done():
+		return zero, context.Cause(ctx)
+	}
+}
+
+// delay returns the backoff after the given attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.Backoff)
+	if p.Multiplier > 1 {
+		delay *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + math.Min(p.Jitter, 1)*(2*rand.Float64()-1)
+	}
+	// The cap applies to the jittered delay, so no wait is ever longer
+	if p.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(p.MaxBackoff))
+	}
+	return time.Duration(delay)
+}
+
+// WithFallback replaces the error of a retried call with a fallback value
+func WithFallback[T, R any](fn func(context.Context, T) Retried[R], fallback func(T, error) R) func(context.Context, T) Retried[R] {
+	return func(ctx context.Context, item T) Retried[R] {
+		retried := fn(ctx, item)
+		if retried.IsErr() {
+			retried.Result = Ok(fallback(item, retried.err))
+			retried.Fallback = true
+		}
+		return retried
+	}
+}
+
+// StreamRetry applies the function to every item with retries on workerCount goroutines.
+// Failures are reported per item instead of ending the stream, and the input order is kept.
+func StreamRetry[T, R any](s *Stream[T], workerCount int, fn func(context.Context, T) (R, error), policy RetryPolicy) *Stream[Retried[R]] {
+	return resilient(s, "StreamRetry", workerCount, Retry(fn, policy))
+}
+
+// StreamRetryOr is StreamRetry with a fallback value for items whose attempts all failed
+func StreamRetryOr[T, R any](s *Stream[T], workerCount int, fn func(context.Context, T) (R, error), policy RetryPolicy, fallback func(T, error) R) *Stream[Retried[R]] {
+	return resilient(s, "StreamRetryOr", workerCount, WithFallback(Retry(fn, policy), fallback))
+}
+
+// StreamResilient applies a wrapped per-item function on workerCount goroutines, keeping the input order
+func StreamResilient[T, R any](s *Stream[T], workerCount int, fn func(context.Context, T) Retried[R]) *Stream[Retried[R]] {
+	return resilient(s, "StreamResilient", workerCount, fn)
+}
+
+// resilient attaches a stage with the given name that applies fn on workerCount goroutines
+func resilient[T, R any](s *Stream[T], name string, workerCount int, fn func(context.Context, T) Retried[R]) *Stream[Retried[R]] {
+	return pipeCtx(s, name, func(ctx context.Context, input iter.Seq[T]) iter.Seq[Retried[R]] {
+		return parallelOrdered(input, workerCount, 2*max(workerCount, 1), func(item T) Retried[R] {
+			return fn(ctx, item)
+		})
+	})
+}
+
+// FromRetried converts retried results into a fallible stream, dropping the attempt history
+func FromRetried[R any](s *Stream[Retried[R]]) *TryStream[R] {
+	return FromResults(pipe(s, "FromRetried", func(input iter.Seq[Retried[R]]) iter.Seq[Result[R]] {
+		return MapSeq(input, func(retried Retried[R]) Result[R] {
+			return retried.Result
+		})
+	}))
+}
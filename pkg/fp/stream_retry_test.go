@@ -0,0 +1,172 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// failing returns a function that fails the given number of times and then
+// returns its input, each call taking 5ms on the clock
+func failing(clock *fakeClock, failures int) func(context.Context, int) (int, error) {
+	calls := 0
+	return func(_ context.Context, item int) (int, error) {
+		clock.Advance(5 * time.Millisecond)
+		calls++
+		if calls <= failures {
+			return 0, errBoom
+		}
+		return item, nil
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	clock := newFakeClock()
+	retried := Retry(failing(clock, 10), RetryPolicy{
+		MaxAttempts: 4,
+		Backoff:     10 * time.Millisecond,
+		Multiplier:  2,
+		MaxBackoff:  30 * time.Millisecond,
+		Clock:       clock,
+	})(context.Background(), 1)
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	if waits := clock.Waits(); !slices.Equal(waits, want) {
+		t.Fatalf("waited %v, want %v", waits, want)
+	}
+	if !errors.Is(retried.err, errBoom) || retried.err.Error() != "attempt 4: boom" {
+		t.Fatalf("got error %v", retried.err)
+	}
+}
+
+func TestRetryAttemptHistory(t *testing.T) {
+	clock := newFakeClock()
+	retried := Retry(failing(clock, 2), RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     10 * time.Millisecond,
+		Clock:       clock,
+	})(context.Background(), 7)
+
+	if !retried.IsOk() || retried.value != 7 {
+		t.Fatalf("got %v", retried.Result)
+	}
+	want := []Attempt{
+		{Number: 1, Delay: 0, Duration: 5 * time.Millisecond, Err: errBoom},
+		{Number: 2, Delay: 10 * time.Millisecond, Duration: 5 * time.Millisecond, Err: errBoom},
+		{Number: 3, Delay: 10 * time.Millisecond, Duration: 5 * time.Millisecond},
+	}
+	if !slices.Equal(retried.Attempts, want) {
+		t.Fatalf("got attempts %+v, want %+v", retried.Attempts, want)
+	}
+}
+
+func TestRetryJitterBounds(t *testing.T) {
+	clock := newFakeClock()
+	Retry(failing(clock, 100), RetryPolicy{
+		MaxAttempts: 100,
+		Backoff:     100 * time.Millisecond,
+		Jitter:      0.5,
+		Clock:       clock,
+	})(context.Background(), 1)
+
+	waits := clock.Waits()
+	for _, wait := range waits {
+		if wait < 50*time.Millisecond || wait > 150*time.Millisecond {
+			t.Fatalf("waited %v, want between 50ms and 150ms", wait)
+		}
+	}
+	if slices.Min(waits) == slices.Max(waits) {
+		t.Fatalf("every delay is %v, want jittered delays", waits[0])
+	}
+
+	clock = newFakeClock()
+	Retry(failing(clock, 100), RetryPolicy{
+		MaxAttempts: 100,
+		Backoff:     10 * time.Millisecond,
+		Multiplier:  2,
+		MaxBackoff:  30 * time.Millisecond,
+		Jitter:      0.5,
+		Clock:       clock,
+	})(context.Background(), 1)
+
+	for _, wait := range clock.Waits() {
+		if wait > 30*time.Millisecond {
+			t.Fatalf("waited %v, want at most the 30ms cap", wait)
+		}
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newFakeClock()
+	retried := Retry(func(ctx context.Context, _ int) (int, error) {
+		<-ctx.Done()
+		return 0, context.Cause(ctx)
+	}, RetryPolicy{
+		MaxAttempts: 2,
+		Timeout:     time.Second,
+		Clock:       clock,
+	})(context.Background(), 1)
+
+	if !errors.Is(retried.err, ErrAttemptTimeout) {
+		t.Fatalf("got error %v, want ErrAttemptTimeout", retried.err)
+	}
+	for _, attempt := range retried.Attempts {
+		if attempt.Err != ErrAttemptTimeout || attempt.Duration != time.Second {
+			t.Fatalf("got attempt %+v", attempt)
+		}
+	}
+	if len(retried.Attempts) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(retried.Attempts))
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	clock := newFakeClock()
+	retried := Retry(failing(clock, 10), RetryPolicy{
+		MaxAttempts: 5,
+		Retryable:   func(err error) bool { return !errors.Is(err, errBoom) },
+		Clock:       clock,
+	})(context.Background(), 1)
+
+	if len(retried.Attempts) != 1 || !errors.Is(retried.err, errBoom) {
+		t.Fatalf("got %d attempts and %v", len(retried.Attempts), retried.err)
+	}
+}
+
+func TestStreamRetryOrFallback(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newFakeClock()
+	oddFails := func(_ context.Context, item int) (int, error) {
+		if item%2 == 1 {
+			return 0, errBoom
+		}
+		return item * 10, nil
+	}
+	results := StreamRetryOr(RangeStream(0, 6), 3, oddFails, RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		Clock:       clock,
+	}, func(item int, err error) int {
+		return -item
+	}).Collect()
+
+	var values []int
+	for i, retried := range results {
+		values = append(values, retried.value)
+		attempts := 1
+		if i%2 == 1 {
+			attempts = 3
+		}
+		if retried.Fallback != (i%2 == 1) || len(retried.Attempts) != attempts {
+			t.Fatalf("item %d: fallback %v after %d attempts", i, retried.Fallback, len(retried.Attempts))
+		}
+	}
+	if want := []int{0, -1, 20, -3, 40, -5}; !slices.Equal(values, want) {
+		t.Fatalf("got %v, want %v", values, want)
+	}
+}
@@ -0,0 +1,225 @@
+package fp
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"slices"
+)
+
+// Sorted sorts the stream by the comparator.
+// The whole stream is buffered, use SortedExternal for streams larger than memory.
+func (s *Stream[T]) Sorted(comparator Comparator[T]) *Stream[T] {
+	return pipe(s, "Sorted", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			items := slices.SortedStableFunc(input, comparator)
+			for _, item := range items {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// SortedBy sorts the stream by a key
+func SortedBy[T any, K cmp.Ordered](s *Stream[T], keyExtractor func(T) K) *Stream[T] {
+	return s.Sorted(func(a, b T) int {
+		return cmp.Compare(keyExtractor(a), keyExtractor(b))
+	})
+}
+
+// TopK emits the k greatest elements by the comparator, greatest first.
+// Only k elements are kept in memory.
+func (s *Stream[T]) TopK(k int, comparator Comparator[T]) *Stream[T] {
+	return pipe(s, "TopK", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			if k <= 0 {
+				return
+			}
+
+			h := &funcHeap[T]{less: func(a, b T) bool { return comparator(a, b) < 0 }}
+			for item := range input {
+				if h.Len() < k {
+					heap.Push(h, item)
+				} else if comparator(item, h.items[0]) > 0 {
+					h.items[0] = item
+					heap.Fix(h, 0)
+				}
+			}
+
+			top := make([]T, h.Len())
+			for i := len(top) - 1; i >= 0; i-- {
+				top[i] = heap.Pop(h).(T)
+			}
+			for _, item := range top {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Scan emits every intermediate result of reducing the stream
+func (s *Stream[T]) Scan(reducer Reducer[T, T], initial T) *Stream[T] {
+	return StreamScan(s, reducer, initial)
+}
+
+// GroupByStream groups the elements of the stream by key
+func GroupByStream[T any, K comparable](s *Stream[T], keyExtractor KeyExtractor[T, K]) map[K][]T {
+	groups := make(map[K][]T)
+	for item := range s.run() {
+		key := keyExtractor(item)
+		groups[key] = append(groups[key], item)
+	}
+	return groups
+}
+
+// PartitionStream splits the stream into elements that satisfy the predicate and those that do not
+func PartitionStream[T any](s *Stream[T], predicate Predicate[T]) ([]T, []T) {
+	var truthy, falsy []T
+	for item := range s.run() {
+		if predicate(item) {
+			truthy = append(truthy, item)
+		} else {
+			falsy = append(falsy, item)
+		}
+	}
+	return truthy, falsy
+}
+
+// CountBy counts the elements of the stream by key
+func CountBy[T any, K comparable](s *Stream[T], keyExtractor KeyExtractor[T, K]) map[K]int {
+	counts := make(map[K]int)
+	for item := range s.run() {
+		counts[keyExtractor(item)]++
+	}
+	return counts
+}
+
+// SortedExternal sorts a stream that may not fit in memory.
+// Runs of chunkSize elements are sorted in memory and spilled to temporary
+// files with encoding/gob, then merged. T must be encodable with gob.
+func SortedExternal[T any](s *Stream[T], comparator Comparator[T], chunkSize int) *TryStream[T] {
+	return FromResults(newStream(func(ctx context.Context, yield func(Result[T]) bool) {
+		var runs []*os.File
+		defer func() {
+			for _, f := range runs {
+				f.Close()
+				os.Remove(f.Name())
+			}
+		}()
+
+		for chunk := range ChunkSeq(s.open(ctx), max(chunkSize, 1)) {
+			slices.SortStableFunc(chunk, comparator)
+			f, err := spillRun(chunk)
+			if f != nil {
+				runs = append(runs, f)
+			}
+			if err != nil {
+				yield(Err[T](err))
+				return
+			}
+		}
+
+		for res := range mergeRuns(runs, comparator) {
+			if !yield(res) || res.IsErr() {
+				return
+			}
+		}
+	}))
+}
+
+// spillRun writes a sorted run to a temporary file and rewinds it
+func spillRun[T any](chunk []T) (*os.File, error) {
+	f, err := os.CreateTemp("", "fp-sort-*")
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(f)
+	encoder := gob.NewEncoder(writer)
+	for _, item := range chunk {
+		if err := encoder.Encode(&item); err != nil {
+			return f, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return f, err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return f, err
+}
+
+// mergeRuns merges sorted runs, keeping one element per run in memory
+func mergeRuns[T any](runs []*os.File, comparator Comparator[T]) iter.Seq[Result[T]] {
+	type head struct {
+		item    T
+		decoder *gob.Decoder
+		run     int
+	}
+
+	return func(yield func(Result[T]) bool) {
+		h := &funcHeap[head]{less: func(a, b head) bool {
+			if c := comparator(a.item, b.item); c != 0 {
+				return c < 0
+			}
+			return a.run < b.run
+		}}
+
+		advance := func(decoder *gob.Decoder, run int) error {
+			var item T
+			err := decoder.Decode(&item)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			heap.Push(h, head{item: item, decoder: decoder, run: run})
+			return nil
+		}
+
+		for i, f := range runs {
+			if err := advance(gob.NewDecoder(bufio.NewReader(f)), i); err != nil {
+				yield(Err[T](err))
+				return
+			}
+		}
+
+		for h.Len() > 0 {
+			next := heap.Pop(h).(head)
+			if !yield(Ok(next.item)) {
+				return
+			}
+			if err := advance(next.decoder, next.run); err != nil {
+				yield(Err[T](err))
+				return
+			}
+		}
+	}
+}
+
+// funcHeap is a min-heap ordered by less
+type funcHeap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+func (h *funcHeap[T]) Len() int           { return len(h.items) }
+func (h *funcHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *funcHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *funcHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+
+func (h *funcHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,385 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// checkNoLeaks fails the test if goroutines started during it are still running when it ends
+func checkNoLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			// Abandoned replayed streams stop their upstream once collected
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		if leaked := runtime.NumGoroutine() - before; leaked > 0 {
+			t.Errorf("%d goroutines leaked", leaked)
+		}
+	})
+}
+
+// counter returns an endless generator of 0, 1, 2, ...
+func counter() func() int {
+	next := 0
+	return func() int {
+		next++
+		return next - 1
+	}
+}
+
+// endless returns endless streams, with and without stages that run goroutines
+func endless() map[string]func() *Stream[int] {
+	return map[string]func() *Stream[int]{
+		"fused": func() *Stream[int] {
+			return InfiniteStream(counter()).Map(func(x int) int { return x * 2 })
+		},
+		"buffered": func() *Stream[int] {
+			return InfiniteStream(counter()).Buffer(4).Map(func(x int) int { return x * 2 })
+		},
+		"parallel": func() *Stream[int] {
+			return InfiniteStream(counter()).Buffer(4).ParallelOrdered(4, 8, func(x int) int { return x * 2 })
+		},
+	}
+}
+
+func TestTakeDoesNotLeak(t *testing.T) {
+	for name, stream := range endless() {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			for range 20 {
+				got := stream().Take(3).Collect()
+				if want := []int{0, 2, 4}; !slices.Equal(got, want) {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindFirstDoesNotLeak(t *testing.T) {
+	for name, stream := range endless() {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			for range 20 {
+				got := stream().FindFirst(func(x int) bool { return x > 10 })
+				if !got.IsPresent() || got.Get() != 12 {
+					t.Fatalf("got %v, want 12", got)
+				}
+			}
+		})
+	}
+}
+
+func TestAnyMatchDoesNotLeak(t *testing.T) {
+	for name, stream := range endless() {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			for range 20 {
+				if !stream().AnyMatch(func(x int) bool { return x == 100 }) {
+					t.Fatal("AnyMatch returned false")
+				}
+			}
+		})
+	}
+}
+
+func TestAllMatchDoesNotLeak(t *testing.T) {
+	for name, stream := range endless() {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			for range 20 {
+				if stream().AllMatch(func(x int) bool { return x < 100 }) {
+					t.Fatal("AllMatch returned true")
+				}
+			}
+		})
+	}
+}
+
+func TestReplayDoesNotLeak(t *testing.T) {
+	for name, stream := range endless() {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			for range 20 {
+				replay := stream().Replay()
+				first, second := replay.Take(3).Collect(), replay.Take(2).Collect()
+				if !slices.Equal(first, []int{0, 2, 4}) || !slices.Equal(second, []int{0, 2}) {
+					t.Fatalf("got %v and %v", first, second)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayResumesAfterEarlyStop(t *testing.T) {
+	replay := RangeStream(0, 10).Replay()
+	if got := replay.Take(3).Collect(); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Fatalf("got %v, want [0 1 2]", got)
+	}
+	if got := replay.Count(); got != 10 || replay.Err() != nil {
+		t.Fatalf("counted %d and %v after an early stop, want 10", got, replay.Err())
+	}
+
+	ch := make(chan int, 5)
+	for i := range 5 {
+		ch <- i
+	}
+	close(ch)
+	replay = NewStreamFromChannel(ch).Replay()
+	if found := replay.FindFirst(func(x int) bool { return x == 1 }); !found.IsPresent() {
+		t.Fatal("FindFirst found nothing")
+	}
+	if got := replay.Collect(); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("got %v after FindFirst, want [0 1 2 3 4]", got)
+	}
+}
+
+func TestReplayStopsWhenConsumersAreCancelled(t *testing.T) {
+	checkNoLeaks(t)
+	ch := make(chan int)
+	defer close(ch)
+	replay := NewStreamFromChannel(ch).Replay()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan []int)
+	go func() {
+		done <- replay.WithContext(ctx).Collect()
+	}()
+	ch <- 1
+	ch <- 2
+	cancel()
+
+	// The item in flight when the consumer is cancelled may be lost, but
+	// whatever the consumer got was recorded for later consumers
+	got := <-done
+	replayed := replay.Collect()
+	if len(replayed) == 0 || !slices.Equal(replayed, []int{1, 2}[:len(replayed)]) || !slices.Equal(got, replayed[:len(got)]) {
+		t.Fatalf("got %v, replayed %v", got, replayed)
+	}
+	if err := replay.Err(); !errors.Is(err, ErrReplayCut) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("a replay of a cut upstream reports %v", err)
+	}
+}
+
+func TestReplayKeepsOtherConsumersRunning(t *testing.T) {
+	checkNoLeaks(t)
+	ch := make(chan int)
+	replay := NewStreamFromChannel(ch).Replay()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	cancelledDone := make(chan struct{})
+	go func() {
+		defer close(cancelledDone)
+		replay.WithContext(ctx).Peek(func(int) { close(started); <-ctx.Done() }).Count()
+	}()
+	ch <- 1
+	<-started
+	replayed := make(chan struct{})
+	done := make(chan []int)
+	go func() {
+		done <- replay.Peek(func(x int) {
+			if x == 1 {
+				close(replayed)
+			}
+		}).Collect()
+	}()
+	<-replayed
+	cancel()
+	<-cancelledDone
+
+	ch <- 2
+	close(ch)
+	if got := <-done; !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+}
+
+func TestReplayConsumesChannelOnce(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	replay := NewStreamFromChannel(ch).Replay()
+	if count := replay.Count(); count != 3 {
+		t.Fatalf("counted %d, want 3", count)
+	}
+	if got := replay.Collect(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+}
+
+// The channel-per-stage engine streams were built on before stages were fused,
+// kept as the baseline for the benchmarks
+
+func chanSource[T any](slice []T) <-chan T {
+	output := make(chan T, len(slice))
+	go func() {
+		defer close(output)
+		for _, item := range slice {
+			output <- item
+		}
+	}()
+	return output
+}
+
+func chanMap[T any](input <-chan T, mapper Mapper[T, T]) <-chan T {
+	output := make(chan T)
+	go func() {
+		defer close(output)
+		for item := range input {
+			output <- mapper(item)
+		}
+	}()
+	return output
+}
+
+func chanFilter[T any](input <-chan T, predicate Predicate[T]) <-chan T {
+	output := make(chan T)
+	go func() {
+		defer close(output)
+		for item := range input {
+			if predicate(item) {
+				output <- item
+			}
+		}
+	}()
+	return output
+}
+
+func chanSkip[T any](input <-chan T, n int) <-chan T {
+	output := make(chan T)
+	go func() {
+		defer close(output)
+		count := 0
+		for item := range input {
+			if count >= n {
+				output <- item
+			}
+			count++
+		}
+	}()
+	return output
+}
+
+var (
+	benchDouble    = func(x int) int { return x * 2 }
+	benchIncrement = func(x int) int { return x + 1 }
+	benchNotFifth  = func(x int) bool { return x%5 != 0 }
+	benchOdd       = func(x int) bool { return x%2 == 1 }
+)
+
+// BenchmarkStreamFiveStages runs Map, Filter, Map, Skip and Filter over a million ints
+func BenchmarkStreamFiveStages(b *testing.B) {
+	input := RangeStream(0, 1_000_000).Collect()
+	b.Run("fused", func(b *testing.B) {
+		for b.Loop() {
+			NewStream(input).Map(benchDouble).Filter(benchNotFifth).Map(benchIncrement).Skip(10).Filter(benchOdd).Count()
+		}
+	})
+	b.Run("channel-per-stage", func(b *testing.B) {
+		for b.Loop() {
+			count := 0
+			for range chanFilter(chanSkip(chanMap(chanFilter(chanMap(chanSource(input), benchDouble), benchNotFifth), benchIncrement), 10), benchOdd) {
+				count++
+			}
+		}
+	})
+}
+
+func TestInputsKeepTheirContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := func() *Stream[int] {
+		return RangeStream(0, 5).WithContext(ctx)
+	}
+
+	combined := map[string]func() int{
+		"MergeStreams":      func() int { return MergeStreams(cancelled()).Count() },
+		"ConcatStreams":     func() int { return ConcatStreams(cancelled(), cancelled()).Count() },
+		"InterleaveStreams": func() int { return InterleaveStreams(cancelled()).Count() },
+		"StreamZip":         func() int { return StreamZip(RangeStream(0, 5), cancelled()).Count() },
+		"SortedExternal": func() int {
+			count, _ := SortedExternal(cancelled(), func(a, b int) int { return a - b }, 2).Count()
+			return count
+		},
+	}
+	for name, count := range combined {
+		t.Run(name, func(t *testing.T) {
+			checkNoLeaks(t)
+			if got := count(); got != 0 {
+				t.Fatalf("got %d items from a cancelled input", got)
+			}
+		})
+	}
+}
+
+func TestErrReportsTheRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stream := RangeStream(0, 5).WithContext(ctx)
+
+	if got := stream.Collect(); len(got) != 5 || stream.Err() != nil {
+		t.Fatalf("got %v and %v from a complete run", got, stream.Err())
+	}
+	<-ctx.Done()
+	if err := stream.Err(); err != nil {
+		t.Fatalf("a complete run reports %v once the deadline passes", err)
+	}
+	if got := stream.Collect(); len(got) != 0 || stream.Err() != context.DeadlineExceeded {
+		t.Fatalf("got %v and %v from a run after the deadline", got, stream.Err())
+	}
+}
+
+func TestErrReportsCancelledInput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	merged := ConcatStreams(RangeStream(0, 5), RangeStream(0, 5).WithContext(ctx))
+
+	if got := merged.Count(); got != 5 || merged.Err() != context.Canceled {
+		t.Fatalf("got %d items and %v", got, merged.Err())
+	}
+	if taken := RangeStream(0, 5).Replay().Take(2); taken.Count() != 2 || taken.Err() != nil {
+		t.Fatalf("a replay stopped by Take reports %v", taken.Err())
+	}
+}
+
+func TestRangeStepStream(t *testing.T) {
+	if got := RangeStepStream(0.0, 1.0, 0.25).Collect(); !slices.Equal(got, []float64{0, 0.25, 0.5, 0.75}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := RangeStepStream(5, 0, -2).Collect(); !slices.Equal(got, []int{5, 3, 1}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := RangeStepStream(0, 5, 0).Collect(); len(got) != 0 {
+		t.Fatalf("got %v from a zero step", got)
+	}
+}
+
+func TestRangeStepStreamStopsAtTheLimitsOfTheType(t *testing.T) {
+	if got := RangeStepStream[int8](0, 127, 100).Collect(); !slices.Equal(got, []int8{0, 100}) {
+		t.Fatalf("got %v, want [0 100]", got)
+	}
+	if got := RangeStepStream[int8](-128, 127, 1).Count(); got != 255 {
+		t.Fatalf("got %d int8 values, want 255", got)
+	}
+	if got := RangeStepStream[int8](0, -128, -100).Collect(); !slices.Equal(got, []int8{0, -100}) {
+		t.Fatalf("got %v, want [0 -100]", got)
+	}
+
+	// float32 cannot tell 1<<24 from 1<<24 + 1
+	last := RangeStepStream[float32](0, 1e9, 1).Reduce(func(_, x float32) float32 { return x }, -1)
+	if last != 1<<24 {
+		t.Fatalf("ended at %v, want %v", last, float32(1<<24))
+	}
+}
@@ -0,0 +1,145 @@
+package fp
+
+import (
+	"iter"
+	"time"
+)
+
+// Throttle emits an item and then drops the items that arrive within interval after it
+func (s *Stream[T]) Throttle(interval time.Duration, clock Clock) *Stream[T] {
+	return pipe(s, "Throttle", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			var last time.Time
+			emitted := false
+			for item := range input {
+				now := clock.Now()
+				if emitted && now.Sub(last) < interval {
+					continue
+				}
+				emitted = true
+				last = now
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Debounce emits an item only once no newer item has arrived for quiet.
+// The last item is emitted when the stream ends.
+func (s *Stream[T]) Debounce(quiet time.Duration, clock Clock) *Stream[T] {
+	return pipe(s, "Debounce", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			items := pump(input, 0, done)
+
+			var pending T
+			var timeout <-chan time.Time
+			for {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						if timeout != nil {
+							yield(pending)
+						}
+						return
+					}
+					pending = item
+					timeout = clock.After(quiet)
+				case <-timeout:
+					timeout = nil
+					if !yield(pending) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// Sample emits, every period, the latest item that arrived since the previous sample.
+// Periods without items produce nothing, and an unsampled last item is emitted when the stream ends.
+func (s *Stream[T]) Sample(every time.Duration, clock Clock) *Stream[T] {
+	return pipe(s, "Sample", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			items := pump(input, 0, done)
+
+			var latest T
+			fresh := false
+			tick := clock.After(every)
+			for {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						if fresh {
+							yield(latest)
+						}
+						return
+					}
+					latest = item
+					fresh = true
+				case <-tick:
+					tick = clock.After(every)
+					if fresh {
+						fresh = false
+						if !yield(latest) {
+							return
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+// Delay shifts every item by d, keeping the gaps between items.
+// Items wait in an unbounded queue while they are delayed.
+func (s *Stream[T]) Delay(d time.Duration, clock Clock) *Stream[T] {
+	type delayed struct {
+		due  time.Time
+		item T
+	}
+
+	return pipe(s, "Delay", func(input iter.Seq[T]) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			// Items are stamped as they arrive, not when the loop gets to them
+			items := pump(MapSeq(input, func(item T) delayed {
+				return delayed{due: clock.Now().Add(d), item: item}
+			}), 0, done)
+
+			var queue []delayed
+			var timer <-chan time.Time
+			for items != nil || len(queue) > 0 {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						items = nil
+						continue
+					}
+					queue = append(queue, item)
+					if len(queue) == 1 {
+						timer = clock.After(d)
+					}
+				case now := <-timer:
+					for len(queue) > 0 && !queue[0].due.After(now) {
+						item := queue[0].item
+						queue = queue[1:]
+						if !yield(item) {
+							return
+						}
+					}
+					timer = nil
+					if len(queue) > 0 {
+						timer = clock.After(queue[0].due.Sub(now))
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package fp
+
+import (
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+// feed returns a stream of the items passed to send, which returns once the
+// stage after the stream has taken the item, and a function that ends the stream
+func feed() (*Stream[int], func(int), func()) {
+	ch := make(chan int)
+	taken := make(chan struct{})
+	s := pipe(NewStreamFromChannel(ch), "feed", func(input iter.Seq[int]) iter.Seq[int] {
+		return func(yield func(int) bool) {
+			for item := range input {
+				if !yield(item) {
+					return
+				}
+				taken <- struct{}{}
+			}
+		}
+	})
+	send := func(item int) {
+		ch <- item
+		<-taken
+	}
+	return s, send, func() { close(ch) }
+}
+
+// stamped is an emitted item with the time of the manual clock when it was emitted
+type stamped[T any] struct {
+	item T
+	at   time.Duration
+}
+
+// emitted runs the stream in the background and returns a function that
+// receives the next emitted item, or reports that the stream has ended
+func emitted[T any](t *testing.T, s *Stream[T], clock *manualClock) func() (stamped[T], bool) {
+	out := StreamMap(s, func(item T) stamped[T] {
+		return stamped[T]{item: item, at: clock.Elapsed()}
+	}).CollectToChannel()
+
+	return func() (stamped[T], bool) {
+		t.Helper()
+		select {
+		case item, ok := <-out:
+			return item, ok
+		case <-time.After(time.Second):
+			t.Fatal("nothing was emitted")
+			return stamped[T]{}, false
+		}
+	}
+}
+
+// expect checks that next emits the items at the given times and then ends
+func expect[T comparable](t *testing.T, next func() (stamped[T], bool), want ...stamped[T]) {
+	t.Helper()
+	for _, w := range want {
+		if got, ok := next(); !ok || got != w {
+			t.Fatalf("got %+v (open: %v), want %+v", got, ok, w)
+		}
+	}
+	if got, ok := next(); ok {
+		t.Fatalf("got %+v after the last expected item", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	clock := newManualClock()
+	got := RangeStream(0, 10).
+		Peek(func(int) { clock.Advance(4 * time.Millisecond) }).
+		Throttle(10*time.Millisecond, clock).
+		Collect()
+
+	// Items arrive every 4ms, so after each emitted one the next two are dropped
+	if want := []int{0, 3, 6, 9}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := emitted(t, s.Debounce(10*time.Millisecond, clock), clock)
+
+	send(1)
+	clock.WaitForAfters(t, 1)
+	clock.Advance(5 * time.Millisecond)
+	send(2)
+	clock.WaitForAfters(t, 2)
+	clock.Advance(5 * time.Millisecond)
+	clock.Advance(5 * time.Millisecond)
+	if got, _ := next(); got != (stamped[int]{2, 15 * time.Millisecond}) {
+		t.Fatalf("got %+v, want 2 after 15ms", got)
+	}
+
+	send(3)
+	clock.WaitForAfters(t, 3)
+	end()
+	expect(t, next, stamped[int]{3, 15 * time.Millisecond})
+}
+
+func TestSample(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := emitted(t, s.Sample(10*time.Millisecond, clock), clock)
+
+	clock.WaitForAfters(t, 1)
+	send(1)
+	send(2)
+	clock.Advance(10 * time.Millisecond)
+	if got, _ := next(); got != (stamped[int]{2, 10 * time.Millisecond}) {
+		t.Fatalf("got %+v, want 2 after 10ms", got)
+	}
+
+	// A period without items emits nothing
+	clock.WaitForAfters(t, 2)
+	clock.Advance(10 * time.Millisecond)
+	clock.WaitForAfters(t, 3)
+	send(3)
+	end()
+	expect(t, next, stamped[int]{3, 20 * time.Millisecond})
+}
+
+func TestDelay(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := emitted(t, s.Delay(10*time.Millisecond, clock), clock)
+
+	send(1)
+	clock.WaitForAfters(t, 1)
+	clock.Advance(4 * time.Millisecond)
+	send(2)
+	clock.Advance(6 * time.Millisecond)
+	if got, _ := next(); got != (stamped[int]{1, 10 * time.Millisecond}) {
+		t.Fatalf("got %+v, want 1 after 10ms", got)
+	}
+
+	// The second item keeps its 4ms gap to the first
+	clock.WaitForAfters(t, 2)
+	clock.Advance(4 * time.Millisecond)
+	if got, _ := next(); got != (stamped[int]{2, 14 * time.Millisecond}) {
+		t.Fatalf("got %+v, want 2 after 14ms", got)
+	}
+	end()
+	expect[int](t, next)
+}
@@ -0,0 +1,270 @@
+package fp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ErrorPolicy defines how a TryStream handles failed items
+type ErrorPolicy int
+
+const (
+	// FailFast stops the stream at the first error
+	FailFast ErrorPolicy = iota
+	// SkipErrors drops failed items and reports all errors when the stream ends
+	SkipErrors
+	// DeadLetter routes failed items to a sink and keeps going
+	DeadLetter
+)
+
+// ItemError describes an item that failed in a TryStream stage
+type ItemError struct {
+	Item any
+	Err  error
+}
+
+// Error returns the error message
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %v: %v", e.Item, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// TryStream is a stream whose stages can fail
+type TryStream[T any] struct {
+	results *Stream[Result[T]]
+	policy  ErrorPolicy
+	sink    func(error)
+}
+
+// tryPipe attaches a stage to the stream and carries the error policy over
+func tryPipe[T, R any](ts *TryStream[T], name string, stage func(iter.Seq[Result[T]]) iter.Seq[Result[R]]) *TryStream[R] {
+	return &TryStream[R]{
+		results: pipe(ts.results, name, stage),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// tryPipeCtx attaches a stage that needs the context of the running stream
+func tryPipeCtx[T, R any](ts *TryStream[T], name string, stage func(context.Context, iter.Seq[Result[T]]) iter.Seq[Result[R]]) *TryStream[R] {
+	return &TryStream[R]{
+		results: pipeCtx(ts.results, name, stage),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// NewTryStream creates a TryStream from a stream with the FailFast policy
+func NewTryStream[T any](s *Stream[T]) *TryStream[T] {
+	return FromResults(pipe(s, "NewTryStream", func(input iter.Seq[T]) iter.Seq[Result[T]] {
+		return MapSeq(input, Ok[T])
+	}))
+}
+
+// FromResults creates a TryStream from a stream of Results
+func FromResults[T any](results *Stream[Result[T]]) *TryStream[T] {
+	return &TryStream[T]{
+		results: results,
+		policy:  FailFast,
+	}
+}
+
+// WithPolicy sets the error policy
+func (ts *TryStream[T]) WithPolicy(policy ErrorPolicy) *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results,
+		policy:  policy,
+		sink:    ts.sink,
+	}
+}
+
+// WithDeadLetter routes failed items to the sink and switches to the DeadLetter policy
+func (ts *TryStream[T]) WithDeadLetter(sink func(error)) *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results,
+		policy:  DeadLetter,
+		sink:    sink,
+	}
+}
+
+// Replay memoizes the stream so it can be consumed any number of times
+func (ts *TryStream[T]) Replay() *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results.Replay(),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// WithContext binds a context to the whole stream
+func (ts *TryStream[T]) WithContext(ctx context.Context) *TryStream[T] {
+	return &TryStream[T]{
+		results: ts.results.WithContext(ctx),
+		policy:  ts.policy,
+		sink:    ts.sink,
+	}
+}
+
+// Map applies a transformation function that may fail
+func (ts *TryStream[T]) Map(mapper func(T) (T, error)) *TryStream[T] {
+	return tryMapCtx(ts, "Map", ignoreContext(mapper))
+}
+
+// MapCtx applies a context-aware transformation function that may fail
+func (ts *TryStream[T]) MapCtx(mapper func(context.Context, T) (T, error)) *TryStream[T] {
+	return tryMapCtx(ts, "MapCtx", mapper)
+}
+
+// Filter applies a filtering function to successful items
+func (ts *TryStream[T]) Filter(predicate Predicate[T]) *TryStream[T] {
+	return tryPipe(ts, "Filter", func(input iter.Seq[Result[T]]) iter.Seq[Result[T]] {
+		return FilterSeq(input, func(res Result[T]) bool {
+			return res.IsErr() || predicate(res.value)
+		})
+	})
+}
+
+// Results returns the underlying stream of Results, ignoring the error policy
+func (ts *TryStream[T]) Results() *Stream[Result[T]] {
+	return ts.results
+}
+
+// Collect collects successful items into a slice.
+// With FailFast the first error stops the stream and is returned,
+// with SkipErrors all errors are joined, with DeadLetter only the
+// error of a cancelled context is returned.
+func (ts *TryStream[T]) Collect() ([]T, error) {
+	var result []T
+	err := ts.run(func(item T) bool {
+		result = append(result, item)
+		return true
+	})
+	if err != nil && ts.policy == FailFast {
+		return nil, err
+	}
+	return result, err
+}
+
+// ForEach executes a function for each successful item
+func (ts *TryStream[T]) ForEach(action func(T)) error {
+	return ts.run(func(item T) bool {
+		action(item)
+		return true
+	})
+}
+
+// Count counts the successful items
+func (ts *TryStream[T]) Count() (int, error) {
+	count := 0
+	err := ts.run(func(T) bool {
+		count++
+		return true
+	})
+	return count, err
+}
+
+// run drives the stream and applies the error policy
+func (ts *TryStream[T]) run(action func(T) bool) error {
+	var errs []error
+	for res := range ts.results.run() {
+		if res.IsErr() {
+			switch ts.policy {
+			case FailFast:
+				return res.err
+			case SkipErrors:
+				errs = append(errs, res.err)
+			case DeadLetter:
+				if ts.sink != nil {
+					ts.sink(res.err)
+				}
+			}
+			continue
+		}
+		if !action(res.value) {
+			break
+		}
+	}
+	if err := ts.results.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// TryMap applies a transformation function that may fail and may change the element type.
+// Failed items are wrapped in an ItemError.
+func TryMap[T, R any](ts *TryStream[T], mapper func(T) (R, error)) *TryStream[R] {
+	return tryMapCtx(ts, "TryMap", ignoreContext(mapper))
+}
+
+// TryMapCtx applies a context-aware transformation function that may fail.
+// The mapper receives the context bound to the stream.
+func TryMapCtx[T, R any](ts *TryStream[T], mapper func(context.Context, T) (R, error)) *TryStream[R] {
+	return tryMapCtx(ts, "TryMapCtx", mapper)
+}
+
+// tryMapCtx attaches a mapping stage with the given name
+func tryMapCtx[T, R any](ts *TryStream[T], name string, mapper func(context.Context, T) (R, error)) *TryStream[R] {
+	return tryPipeCtx(ts, name, func(ctx context.Context, input iter.Seq[Result[T]]) iter.Seq[Result[R]] {
+		return MapSeq(input, func(res Result[T]) Result[R] {
+			if res.IsErr() {
+				return Err[R](res.err)
+			}
+			value, err := mapper(ctx, res.value)
+			if err != nil {
+				return Err[R](&ItemError{Item: res.value, Err: err})
+			}
+			return Ok(value)
+		})
+	})
+}
+
+// TryFlatMap applies a function that may fail and flattens the results into the stream
+func TryFlatMap[T, R any](ts *TryStream[T], mapper func(T) ([]R, error)) *TryStream[R] {
+	return tryPipe(ts, "TryFlatMap", func(input iter.Seq[Result[T]]) iter.Seq[Result[R]] {
+		return func(yield func(Result[R]) bool) {
+			for res := range input {
+				if res.IsErr() {
+					if !yield(Err[R](res.err)) {
+						return
+					}
+					continue
+				}
+				values, err := mapper(res.value)
+				if err != nil {
+					if !yield(Err[R](&ItemError{Item: res.value, Err: err})) {
+						return
+					}
+					continue
+				}
+				for _, value := range values {
+					if !yield(Ok(value)) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// StreamMapErr applies a transformation function that may fail to a regular stream
+func StreamMapErr[T, R any](s *Stream[T], mapper func(T) (R, error)) *TryStream[R] {
+	return tryMapCtx(NewTryStream(s), "StreamMapErr", ignoreContext(mapper))
+}
+
+// StreamMapCtx applies a context-aware transformation function that may fail to a regular stream
+func StreamMapCtx[T, R any](s *Stream[T], mapper func(context.Context, T) (R, error)) *TryStream[R] {
+	return tryMapCtx(NewTryStream(s), "StreamMapCtx", mapper)
+}
+
+// ignoreContext adapts a mapper that does not need the context
+func ignoreContext[T, R any](mapper func(T) (R, error)) func(context.Context, T) (R, error) {
+	return func(_ context.Context, item T) (R, error) {
+		return mapper(item)
+	}
+}
@@ -0,0 +1,174 @@
+package fp
+
+import (
+	"iter"
+	"time"
+)
+
+// TumblingWindow groups the stream into consecutive windows of size items.
+// The last window may be smaller, so an endless stream can be processed in batches.
+func TumblingWindow[T any](s *Stream[T], size int) *Stream[[]T] {
+	return pipe(s, "TumblingWindow", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return ChunkSeq(input, size)
+	})
+}
+
+// SlidingWindow emits windows of size items, starting a new window every step items.
+// Incomplete windows at the end of the stream are dropped.
+func SlidingWindow[T any](s *Stream[T], size, step int) *Stream[[]T] {
+	return pipe(s, "SlidingWindow", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return func(yield func([]T) bool) {
+			if size <= 0 || step <= 0 {
+				return
+			}
+
+			buffer := make([]T, 0, size)
+			skip := 0
+			for item := range input {
+				if skip > 0 {
+					skip--
+					continue
+				}
+				buffer = append(buffer, item)
+				if len(buffer) < size {
+					continue
+				}
+
+				window := make([]T, size)
+				copy(window, buffer)
+				if !yield(window) {
+					return
+				}
+
+				if step >= size {
+					buffer = buffer[:0]
+					skip = step - size
+				} else {
+					buffer = append(buffer[:0], buffer[step:]...)
+				}
+			}
+		}
+	})
+}
+
+// TumblingTimeWindow groups the items that arrive within each period into a window.
+// Periods without items produce no window.
+func TumblingTimeWindow[T any](s *Stream[T], period time.Duration, clock Clock) *Stream[[]T] {
+	return pipe(s, "TumblingTimeWindow", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return func(yield func([]T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			items := pump(input, 0, done)
+
+			var window []T
+			tick := clock.After(period)
+			for {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						if len(window) > 0 {
+							yield(window)
+						}
+						return
+					}
+					window = append(window, item)
+				case <-tick:
+					tick = clock.After(period)
+					if len(window) > 0 {
+						if !yield(window) {
+							return
+						}
+						window = nil
+					}
+				}
+			}
+		}
+	})
+}
+
+// SlidingTimeWindow emits, every period, the items that arrived within the last size.
+// A window is emitted only if it contains at least one item that has not been emitted before.
+func SlidingTimeWindow[T any](s *Stream[T], size, period time.Duration, clock Clock) *Stream[[]T] {
+	type stamped struct {
+		at   time.Time
+		item T
+	}
+
+	return pipe(s, "SlidingTimeWindow", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return func(yield func([]T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			// Items are stamped as they arrive, not when the loop gets to them
+			items := pump(MapSeq(input, func(item T) stamped {
+				return stamped{at: clock.Now(), item: item}
+			}), 0, done)
+
+			var buffer []stamped
+			fresh := false
+			emit := func() bool {
+				if !fresh {
+					return true
+				}
+				fresh = false
+				return yield(Map(buffer, func(e stamped) T { return e.item }))
+			}
+
+			tick := clock.After(period)
+			for {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						emit()
+						return
+					}
+					buffer = append(buffer, item)
+					fresh = true
+				case now := <-tick:
+					tick = clock.After(period)
+					cutoff := now.Add(-size)
+					start := 0
+					for start < len(buffer) && !buffer[start].at.After(cutoff) {
+						start++
+					}
+					buffer = buffer[start:]
+					if !emit() {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// SessionWindow groups items into sessions that end once no item arrives for gap
+func SessionWindow[T any](s *Stream[T], gap time.Duration, clock Clock) *Stream[[]T] {
+	return pipe(s, "SessionWindow", func(input iter.Seq[T]) iter.Seq[[]T] {
+		return func(yield func([]T) bool) {
+			done := make(chan struct{})
+			defer close(done)
+			items := pump(input, 0, done)
+
+			var session []T
+			var timeout <-chan time.Time
+			for {
+				select {
+				case item, ok := <-items:
+					if !ok {
+						if len(session) > 0 {
+							yield(session)
+						}
+						return
+					}
+					session = append(session, item)
+					timeout = clock.After(gap)
+				case <-timeout:
+					timeout = nil
+					if !yield(session) {
+						return
+					}
+					session = nil
+				}
+			}
+		}
+	})
+}
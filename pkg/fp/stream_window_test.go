@@ -0,0 +1,98 @@
+package fp
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// windows runs the windowed stream in the background like emitted, with every
+// window printed so it can be compared
+func windows(t *testing.T, s *Stream[[]int], clock *manualClock) func() (stamped[string], bool) {
+	return emitted(t, StreamMap(s, func(window []int) string { return fmt.Sprint(window) }), clock)
+}
+
+func TestSlidingWindowWithGaps(t *testing.T) {
+	got := SlidingWindow(RangeStream(0, 10), 2, 3).Collect()
+	if want := [][]int{{0, 1}, {3, 4}, {6, 7}}; !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTumblingTimeWindow(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := windows(t, TumblingTimeWindow(s, 10*time.Millisecond, clock), clock)
+
+	clock.WaitForAfters(t, 1)
+	send(1)
+	send(2)
+	clock.Advance(10 * time.Millisecond)
+	if got, _ := next(); got != (stamped[string]{"[1 2]", 10 * time.Millisecond}) {
+		t.Fatalf("got %+v, want [1 2] after 10ms", got)
+	}
+
+	// A period without items produces no window
+	clock.WaitForAfters(t, 2)
+	clock.Advance(10 * time.Millisecond)
+	clock.WaitForAfters(t, 3)
+	send(3)
+	end()
+	expect(t, next, stamped[string]{"[3]", 20 * time.Millisecond})
+}
+
+func TestSlidingTimeWindow(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := windows(t, SlidingTimeWindow(s, 20*time.Millisecond, 10*time.Millisecond, clock), clock)
+
+	clock.WaitForAfters(t, 1)
+	send(1)
+	clock.Advance(5 * time.Millisecond)
+	send(2)
+	clock.Advance(5 * time.Millisecond)
+	if got, _ := next(); got != (stamped[string]{"[1 2]", 10 * time.Millisecond}) {
+		t.Fatalf("got %+v, want [1 2] after 10ms", got)
+	}
+
+	// 1 arrived 20ms ago and leaves the window, 2 stays in it
+	clock.WaitForAfters(t, 2)
+	send(3)
+	clock.Advance(10 * time.Millisecond)
+	if got, _ := next(); got != (stamped[string]{"[2 3]", 20 * time.Millisecond}) {
+		t.Fatalf("got %+v, want [2 3] after 20ms", got)
+	}
+
+	// A window without new items is not emitted again
+	clock.WaitForAfters(t, 3)
+	clock.Advance(10 * time.Millisecond)
+	clock.WaitForAfters(t, 4)
+	end()
+	expect[string](t, next)
+}
+
+func TestSessionWindow(t *testing.T) {
+	checkNoLeaks(t)
+	clock := newManualClock()
+	s, send, end := feed()
+	next := windows(t, SessionWindow(s, 10*time.Millisecond, clock), clock)
+
+	send(1)
+	clock.WaitForAfters(t, 1)
+	clock.Advance(5 * time.Millisecond)
+	send(2)
+	clock.WaitForAfters(t, 2)
+	clock.Advance(5 * time.Millisecond)
+	clock.Advance(5 * time.Millisecond)
+	if got, _ := next(); got != (stamped[string]{"[1 2]", 15 * time.Millisecond}) {
+		t.Fatalf("got %+v, want [1 2] after 15ms", got)
+	}
+
+	send(3)
+	clock.WaitForAfters(t, 3)
+	end()
+	expect(t, next, stamped[string]{"[3]", 15 * time.Millisecond})
+}